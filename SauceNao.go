@@ -4,15 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
 	"reflect"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Miuzarte/SauceNAO-go/db"
 
@@ -30,13 +33,25 @@ type Client struct {
 	ApiKey             string
 	Host               string
 	NumRes             int
-	Hide               bool
+	DbMask             uint64 // 只搜索部分索引, 0 为全部, 见 [db.Mask]
+	Hide               HideLevel
+	SafeFilter         bool         // 客户端侧丢弃 Header.Hidden 非 0 的结果
+	HtmlFallback       bool         // 未设置 ApiKey 时改为请求公开搜索页 (output_type=0) 并解析 html
+	KeepRawBody        bool         // 在 [Response.RawBody] 中保留响应原文, 批量任务中会使内存占用翻倍
+	HttpClient         *http.Client // 为 nil 时使用 http.DefaultClient, 不影响 FlareSolverr 的请求
 	FlareSolverrClient *fs.Client
+	UserCookies        []*http.Cookie // 登录 saucenao 后的 cookies, 经 FlareSolverr 访问时带上, 见 [Client.Account]
+	Cache              Cache          // 为 nil 时不缓存, 见 [NewMemoryCache]
+	Metrics            *Metrics       // 为 nil 时不收集
+	Logger             *slog.Logger   // 为 nil 时不输出, 链接中的 api_key 会被隐去
+	Hooks              Hooks
 
 	cache struct {
 		userAgent string
 		cookies   []*http.Cookie
 	}
+	quotaMu sync.Mutex
+	quota   Quota
 }
 
 type HttpError struct {
@@ -49,7 +64,13 @@ func (e *HttpError) Error() string {
 	return fmt.Sprintf("http error %d: %s, %s", e.StatusCode, e.Url, e.Body)
 }
 
-func NewClient(apiKey, overrideHost string, numRes int, hide bool, fsClient *fs.Client) *Client {
+// IsRateLimited 是否因超出搜索次数限制而失败 (http 429)
+func IsRateLimited(err error) bool {
+	var he *HttpError
+	return errors.As(err, &he) && he.StatusCode == http.StatusTooManyRequests
+}
+
+func NewClient(apiKey, overrideHost string, numRes int, hide HideLevel, fsClient *fs.Client) *Client {
 	host := overrideHost
 	if host == "" {
 		host = API_HOST
@@ -68,11 +89,11 @@ func NewClient(apiKey, overrideHost string, numRes int, hide bool, fsClient *fs.
 	}
 }
 
-func (c *Client) Search(ctx context.Context, image any) (resp *Response, err error) {
+func (c *Client) Search(ctx context.Context, image any, opts ...Option) (resp *Response, err error) {
 	switch img := image.(type) {
 	case string:
 		if strings.HasPrefix(img, "http") {
-			return c.Get(ctx, img)
+			return c.Get(ctx, img, opts...)
 		} else {
 			// read local
 			f, err := os.Open(img)
@@ -80,61 +101,136 @@ func (c *Client) Search(ctx context.Context, image any) (resp *Response, err err
 				return nil, err
 			}
 			defer f.Close()
-			return c.Search(ctx, f)
+			return c.Search(ctx, f, opts...)
 		}
 
 	case []byte:
-		return c.Post(ctx, img)
+		return c.Post(ctx, img, opts...)
 	case io.Reader:
 		imgData, err := io.ReadAll(img)
 		if err != nil {
 			return nil, err
 		}
-		return c.Post(ctx, imgData)
+		return c.Post(ctx, imgData, opts...)
 
 	default:
 		return nil, fmt.Errorf("unsupported image type: %T", image)
 	}
 }
 
-func (c *Client) Post(ctx context.Context, imgData []byte) (*Response, error) {
-	return c.do(ctx, func() (*http.Request, error) {
-		return c.buildPostRequest(ctx, imgData)
+func (c *Client) Post(ctx context.Context, imgData []byte, opts ...Option) (*Response, error) {
+	ro := c.requestOptions(opts)
+	resp, err := c.cached(imageCacheKey(ro, imgData), func() (*Response, error) {
+		return c.post(ctx, ro, imgData)
+	})
+	c.Metrics.observeSearch(resp, err)
+	return resp, err
+}
+
+func (c *Client) Get(ctx context.Context, imgUrl string, opts ...Option) (*Response, error) {
+	ro := c.requestOptions(opts)
+	resp, err := c.cached(urlCacheKey(ro, imgUrl), func() (*Response, error) {
+		return c.get(ctx, ro, imgUrl)
 	})
+	c.Metrics.observeSearch(resp, err)
+	return resp, err
 }
 
-func (c *Client) Get(ctx context.Context, imgUrl string) (*Response, error) {
-	return c.do(ctx, func() (*http.Request, error) {
-		return c.buildGetRequest(ctx, imgUrl)
+func (c *Client) post(ctx context.Context, ro *requestOptions, imgData []byte) (*Response, error) {
+	if ro.html && c.FlareSolverrClient != nil && c.cache.cookies == nil {
+		// FlareSolverr 无法上传文件, 先经其取得 cookies 与 user agent, 再由 HttpClient 发送,
+		// 之后的 403 仍在 doRequest 中重新过 cf
+		_, _, err := c.bypassCf(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c.do(ctx, ro, func() (*http.Request, error) {
+		return c.buildPostRequest(ctx, ro, imgData)
+	})
+}
+
+func (c *Client) get(ctx context.Context, ro *requestOptions, imgUrl string) (*Response, error) {
+	if ro.html && c.FlareSolverrClient != nil {
+		// 公开搜索页基本都会触发 cf, 直接走 FlareSolverr
+		req, err := c.buildGetRequest(ctx, ro, imgUrl)
+		if err != nil {
+			return nil, err
+		}
+		body, err := c.fsGet(ctx, req.URL.String())
+		if err != nil {
+			return nil, err
+		}
+		return c.parseResponse(ro, []byte(body))
+	}
+	return c.do(ctx, ro, func() (*http.Request, error) {
+		return c.buildGetRequest(ctx, ro, imgUrl)
 	})
 }
 
-func (c *Client) do(ctx context.Context, requestBuilder func() (*http.Request, error)) (*Response, error) {
+func (c *Client) do(ctx context.Context, ro *requestOptions, requestBuilder func() (*http.Request, error)) (*Response, error) {
+	body, err := c.doRequest(ctx, requestBuilder)
+	if err != nil {
+		return nil, err
+	}
+	return c.parseResponse(ro, body)
+}
+
+// doRequest 发送请求并返回响应体, 遇到 403 时尝试过 cf 后重试
+func (c *Client) doRequest(ctx context.Context, requestBuilder func() (*http.Request, error)) ([]byte, error) {
 	const bypassCfRetryTimes = 1
 	numRetries := bypassCfRetryTimes
+	attempt := 0
 TRYAGAIN:
+	attempt++
 	req, err := requestBuilder()
 	if err != nil {
 		return nil, err
 	}
 
-	hResp, err := http.DefaultClient.Do(req)
+	reqEv := RequestEvent{
+		Upstream: UPSTREAM_SAUCENAO,
+		Method:   req.Method,
+		Url:      redactUrl(req.URL),
+		Attempt:  attempt,
+	}
+	log := c.logger().With(
+		LOG_UPSTREAM, reqEv.Upstream,
+		LOG_METHOD, reqEv.Method,
+		LOG_URL, reqEv.Url,
+		LOG_ATTEMPT, reqEv.Attempt,
+	)
+	log.DebugContext(ctx, "request start")
+	c.Hooks.request(reqEv)
+	start := time.Now()
+	hResp, err := c.httpClient().Do(req)
+	elapsed := time.Since(start)
+	c.Metrics.observeLatency(UPSTREAM_SAUCENAO, elapsed)
 	if err != nil {
+		log.WarnContext(ctx, "request failed", LOG_DURATION, elapsed, LOG_ERROR, err)
+		c.Hooks.response(ResponseEvent{RequestEvent: reqEv, Duration: elapsed, Err: err})
 		return nil, err
 	}
 	defer hResp.Body.Close()
+	log.DebugContext(ctx, "request end", LOG_STATUS, hResp.StatusCode, LOG_DURATION, elapsed)
+	c.Hooks.response(ResponseEvent{RequestEvent: reqEv, StatusCode: hResp.StatusCode, Duration: elapsed})
 
 	switch hResp.StatusCode {
 	case http.StatusOK:
 		break
 
 	case http.StatusForbidden:
+		c.Metrics.observeForbidden()
+		c.Hooks.challenge(ChallengeEvent{Url: reqEv.Url, Attempt: attempt})
 		// 尝试过 cf
 		_, _, e := c.bypassCf(ctx)
+		c.Metrics.observeBypass(e)
 		if e == nil {
 			if numRetries > 0 {
 				// 成功后重试一次
 				numRetries--
+				log.InfoContext(ctx, "retrying after cf bypass")
+				c.Hooks.retry(RetryEvent{Url: reqEv.Url, Attempt: attempt + 1, Reason: "cf challenge solved"})
 				goto TRYAGAIN
 			}
 		}
@@ -145,22 +241,46 @@ TRYAGAIN:
 		if len(body) > bodyTruncateLen {
 			body = body[:bodyTruncateLen]
 		}
+		if hResp.StatusCode == http.StatusTooManyRequests {
+			c.quotaExceeded(string(body))
+		}
+		log.WarnContext(ctx, "request error", LOG_STATUS, hResp.StatusCode, LOG_DURATION, elapsed)
 		return nil, &HttpError{
 			StatusCode: hResp.StatusCode,
-			Url:        req.URL.String(),
+			Url:        redactUrl(req.URL),
 			Body:       string(body),
 		}
 	}
 
-	body, err := io.ReadAll(hResp.Body)
-	if err != nil {
-		return nil, err
-	}
+	return io.ReadAll(hResp.Body)
+}
+
+func (c *Client) parseResponse(ro *requestOptions, body []byte) (*Response, error) {
 	resp := &Response{}
-	resp.RawBody = string(body)
-	err = json.Unmarshal(body, resp)
-	if err != nil {
-		return nil, err
+	if ro.html {
+		err := parseHtmlResponse(body, resp)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err := json.Unmarshal(body, resp)
+		if err != nil {
+			return nil, err
+		}
+		c.updateQuota(&resp.Header)
+	}
+	if ro.rawBody {
+		resp.RawBody = string(body)
+	}
+	for i := range resp.Results {
+		resp.Results[i].client = c
+	}
+	if !ro.html {
+		// html 中的 data 是拼出来的, 不比较
+		c.hooks().schemaDrift(resp.Results)
+	}
+	if ro.safeFilter {
+		filterHidden(resp)
 	}
 	return resp, nil
 }
@@ -170,16 +290,38 @@ func (c *Client) fsGet(ctx context.Context, url string) (string, error) {
 	if c.FlareSolverrClient == nil {
 		return "", fmt.Errorf("FlareSolverrClient is not set")
 	}
-	resp, err := c.FlareSolverrClient.Get(ctx, url, map[string]any{
+	params := map[string]any{
 		fs.PARAM_MAX_TIMEOUT: 60000,
-	})
+	}
+	if len(c.UserCookies) > 0 {
+		cookies := make([]map[string]string, 0, len(c.UserCookies))
+		for _, cookie := range c.UserCookies {
+			cookies = append(cookies, map[string]string{"name": cookie.Name, "value": cookie.Value})
+		}
+		params[fs.PARAM_COOKIES] = cookies
+	}
+	reqEv := RequestEvent{Upstream: UPSTREAM_FLARESOLVERR, Method: http.MethodGet, Url: redactUrlString(url), Attempt: 1}
+	log := c.logger().With(LOG_UPSTREAM, reqEv.Upstream, LOG_URL, reqEv.Url)
+	log.DebugContext(ctx, "solver start")
+	c.Hooks.request(reqEv)
+	start := time.Now()
+	resp, err := c.FlareSolverrClient.Get(ctx, url, params)
+	elapsed := time.Since(start)
+	c.Metrics.observeLatency(UPSTREAM_FLARESOLVERR, elapsed)
 	if err != nil {
+		log.WarnContext(ctx, "solver failed", LOG_DURATION, elapsed, LOG_ERROR, err)
+		c.Hooks.response(ResponseEvent{RequestEvent: reqEv, Duration: elapsed, Err: err})
 		return "", err
 	}
 	if resp.Solution.Status != http.StatusOK {
-		return "", fmt.Errorf("flaresolverr failed %d: %s, %s",
+		log.WarnContext(ctx, "solver failed", LOG_STATUS, resp.Solution.Status, LOG_DURATION, elapsed)
+		err = fmt.Errorf("flaresolverr failed %d: %s, %s",
 			resp.Solution.Status, resp.Message, resp.Solution.Response)
+		c.Hooks.response(ResponseEvent{RequestEvent: reqEv, StatusCode: resp.Solution.Status, Duration: elapsed, Err: err})
+		return "", err
 	}
+	log.InfoContext(ctx, "solver end", LOG_STATUS, resp.Solution.Status, LOG_DURATION, elapsed)
+	c.Hooks.response(ResponseEvent{RequestEvent: reqEv, StatusCode: resp.Solution.Status, Duration: elapsed})
 
 	// cache user agent and cookies
 	c.cache.userAgent = resp.Solution.UserAgent
@@ -190,13 +332,25 @@ func (c *Client) fsGet(ctx context.Context, url string) (string, error) {
 // bypassCf 访问 user.php 获取 cf challenge 凭证
 func (c *Client) bypassCf(ctx context.Context) (ua string, cookies []*http.Cookie, err error) {
 	userUrl := c.Host + USER_PATH
+	c.logger().InfoContext(ctx, "cf challenge, bypassing via flaresolverr", LOG_URL, userUrl)
+	start := time.Now()
 	_, err = c.fsGet(ctx, userUrl)
 	if err != nil {
+		c.logger().WarnContext(ctx, "cf bypass failed", LOG_ERROR, err)
+		c.Hooks.challengeSolved(ChallengeSolvedEvent{Url: userUrl, Duration: time.Since(start), Err: err})
 		return "", nil, err
 	}
+	c.Hooks.challengeSolved(ChallengeSolvedEvent{Url: userUrl, UserAgent: c.cache.userAgent, Duration: time.Since(start)})
 	return c.cache.userAgent, c.cache.cookies, nil
 }
 
+func (c *Client) httpClient() *http.Client {
+	if c.HttpClient == nil {
+		return http.DefaultClient
+	}
+	return c.HttpClient
+}
+
 // requestSetHeader 设置请求头
 func (c *Client) requestSetHeader(req *http.Request) *http.Request {
 	if c.cache.userAgent != "" {
@@ -208,7 +362,7 @@ func (c *Client) requestSetHeader(req *http.Request) *http.Request {
 	return req
 }
 
-func (c *Client) buildPostRequest(ctx context.Context, imgData []byte) (*http.Request, error) {
+func (c *Client) buildPostRequest(ctx context.Context, ro *requestOptions, imgData []byte) (*http.Request, error) {
 	buf := bytes.Buffer{}
 	writer := multipart.NewWriter(&buf)
 
@@ -232,28 +386,32 @@ func (c *Client) buildPostRequest(ctx context.Context, imgData []byte) (*http.Re
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
 	query := req.URL.Query()
-	query.Add("api_key", c.ApiKey)
-	query.Add("output_type", "2")
-	if c.NumRes > 0 {
-		query.Add("numres", strconv.Itoa(c.NumRes))
+	if ro.html {
+		query.Add("output_type", "0")
+	} else {
+		query.Add("api_key", c.ApiKey)
+		query.Add("output_type", "2")
 	}
-	query.Add("hide", strconv.FormatBool(c.Hide))
+	ro.setQuery(query)
 	req.URL.RawQuery = query.Encode()
 
 	return c.requestSetHeader(req), nil
 }
 
-func (c *Client) buildGetRequest(ctx context.Context, imgUrl string) (*http.Request, error) {
+func (c *Client) buildGetRequest(ctx context.Context, ro *requestOptions, imgUrl string) (*http.Request, error) {
 	u, err := url.Parse(c.Host + API_PATH)
 	if err != nil {
 		return nil, err
 	}
 	query := u.Query()
 	query.Add("url", imgUrl)
-	query.Add("api_key", c.ApiKey)
-	query.Add("output_type", "2")
-	query.Add("numres", strconv.Itoa(c.NumRes))
-	query.Add("hide", strconv.FormatBool(c.Hide))
+	if ro.html {
+		query.Add("output_type", "0")
+	} else {
+		query.Add("api_key", c.ApiKey)
+		query.Add("output_type", "2")
+	}
+	ro.setQuery(query)
 	u.RawQuery = query.Encode()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
@@ -264,10 +422,13 @@ func (c *Client) buildGetRequest(ctx context.Context, imgUrl string) (*http.Requ
 	return c.requestSetHeader(req), nil
 }
 
+// Response 搜索结果.
+// json.Marshal 输出的 results 为 [Result.MarshalJSON] 的存储格式
+// (kind, index_id, extra, missing), 并非 api 的原始格式, 读取时两种格式均可
 type Response struct {
 	Header  ResponseHeader `json:"header"`
 	Results []Result       `json:"results"`
-	RawBody string         `json:"-"` // for debug
+	RawBody string         `json:"raw_body,omitempty"` // for debug, 仅在 [Client.KeepRawBody] 时保留
 }
 
 type ResponseHeader struct {
@@ -295,9 +456,14 @@ type IndexEntry struct {
 	Results  int `json:"results"`
 }
 
+// Result 单条结果, 序列化格式见 [Result.MarshalJSON]
 type Result struct {
 	Header ResultHeader   `json:"header"`
 	Data   map[string]any `json:"data"` // delay decode using [mapstructure.Decode]
+
+	client  *Client         // 用于 [Result.DecodeData] 失败时输出日志, 可为 nil
+	typed   db.ResultData   // 由 [Result.UnmarshalJSON] 读取的结构体
+	rawData json.RawMessage // 原始的 data, 解码时数字不经过 float64, 以免超过 2^53 的 id 丢失精度
 }
 
 type ResultHeader struct {
@@ -309,14 +475,30 @@ type ResultHeader struct {
 	Hidden     int        `json:"hidden"`
 }
 
-func decodeTo[T any](input map[string]any) *T {
+// unmarshalJson 与 [json.Unmarshal] 相同, 但数字解码为 [json.Number]
+func unmarshalJson(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err := dec.Decode(v)
+	if err != nil {
+		return err
+	}
+	if _, err = dec.Token(); err != io.EOF {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
+
+// decodeTo 解码到 *T, T 的值方法需实现 [db.ResultData]
+func decodeTo[T any](input map[string]any) (db.ResultData, error) {
 	output := new(T)
-	// 未完成的结构体先返回 nil
+	// 未完成的结构体不解码, 返回零值
+	// (返回 nil 会得到非 nil 的 interface, 调用方法时 panic)
 	rv := reflect.ValueOf(output).Elem()
 	if rv.IsValid() && rv.Kind() == reflect.Struct {
 		fv := rv.FieldByName("Todo")
 		if fv.IsValid() {
-			return nil
+			return any(output).(db.ResultData), nil
 		}
 	}
 
@@ -327,103 +509,54 @@ func decodeTo[T any](input map[string]any) *T {
 	}
 	decoder, err := mapstructure.NewDecoder(&config)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	err = decoder.Decode(input)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	return output
+	return any(output).(db.ResultData), nil
 }
 
-func (r Result) DecodeData() (ret interface{ String() string }) {
+// DecodeData 按索引解码为对应的结构体, 未知索引或解码失败时返回 [db.ResultDataUnknown] (失败时 Err 非 nil)
+func (r Result) DecodeData() (ret db.ResultData) {
+	if r.typed != nil {
+		return copyResultData(r.typed)
+	}
+	// mapstructure 的错误已作为 error 返回, recover 只是兜底
 	defer func() {
 		if rec := recover(); rec != nil {
-			ret = &db.ResultDataUnknown{
-				Raw: r.Data,
-				Err: fmt.Errorf("failed to decode data: %v", rec),
-			}
+			ret = r.decodeFailed(fmt.Errorf("failed to decode data: panic: %v", rec))
 		}
 	}()
-	switch r.Header.IndexId {
-	case db.HMAGAZINES:
-		return decodeTo[db.ResultDataHMagazines](r.Data)
-	case db.HGAMECG:
-		return decodeTo[db.ResultDataHGameCg](r.Data)
-	case db.DOUJINSHIDB:
-		return decodeTo[db.ResultDataDoujinshiDb](r.Data)
-	case db.PIXIV:
-		return decodeTo[db.ResultDataPixiv](r.Data)
-	case db.SEIGA:
-		return decodeTo[db.ResultDataSeiga](r.Data)
-	case db.DANBOORU:
-		return decodeTo[db.ResultDataDanbooru](r.Data)
-	case db.DRAWR:
-		return decodeTo[db.ResultDataDrawr](r.Data)
-	case db.NIJIE:
-		return decodeTo[db.ResultDataNijie](r.Data)
-	case db.YANDERE:
-		return decodeTo[db.ResultDataYandere](r.Data)
-	case db.SHUTTERSTOCK:
-		return decodeTo[db.ResultDataShutterstock](r.Data)
-	case db.FAKKU:
-		return decodeTo[db.ResultDataFakku](r.Data)
-	case db.NHENTAI:
-		return decodeTo[db.ResultDataNHentai](r.Data)
-	case db.MARKET2D:
-		return decodeTo[db.ResultDataMarket2d](r.Data)
-	case db.MEDIBANG:
-		return decodeTo[db.ResultDataMediBang](r.Data)
-	case db.ANIME:
-		return decodeTo[db.ResultDataAnime](r.Data)
-	case db.HANIME:
-		return decodeTo[db.ResultDataHAnime](r.Data)
-	case db.MOVIES:
-		return decodeTo[db.ResultDataMovies](r.Data)
-	case db.SHOWS:
-		return decodeTo[db.ResultDataShows](r.Data)
-	case db.GELBOORU:
-		return decodeTo[db.ResultDataGelbooru](r.Data)
-	case db.KONACHAN:
-		return decodeTo[db.ResultDataKonachan](r.Data)
-	case db.SANKAKU:
-		return decodeTo[db.ResultDataSankaku](r.Data)
-	case db.ANIMEPICTURES:
-		return decodeTo[db.ResultDataAnimePictures](r.Data)
-	case db.E621:
-		return decodeTo[db.ResultDataE621](r.Data)
-	case db.IDOLCOMPLEX:
-		return decodeTo[db.ResultDataIdolComplex](r.Data)
-	case db.BCY_ILLUST:
-		return decodeTo[db.ResultDataBcyIllust](r.Data)
-	case db.BCY_COSPLAY:
-		return decodeTo[db.ResultDataBcyCosplay](r.Data)
-	case db.PORTALGRAPHICS:
-		return decodeTo[db.ResultDataPortalGraphics](r.Data)
-	case db.DEVIANTART:
-		return decodeTo[db.ResultDataDeviantArt](r.Data)
-	case db.PAWOO:
-		return decodeTo[db.ResultDataPawoo](r.Data)
-	case db.MADOKAMI:
-		return decodeTo[db.ResultDataMadokami](r.Data)
-	case db.MANGADEX:
-		return decodeTo[db.ResultDataMangaDex](r.Data)
-	case db.EHENTAI:
-		return decodeTo[db.ResultDataEHentai](r.Data)
-	case db.ARTSTATION:
-		return decodeTo[db.ResultDataArtStation](r.Data)
-	case db.FURAFFINITY:
-		return decodeTo[db.ResultDataFurAffinity](r.Data)
-	case db.TWITTER:
-		return decodeTo[db.ResultDataTwitter](r.Data)
-	case db.FURRYNETWORK:
-		return decodeTo[db.ResultDataFurryNetwork](r.Data)
-	case db.KEMONO:
-		return decodeTo[db.ResultDataKemono](r.Data)
-	case db.SKEB:
-		return decodeTo[db.ResultDataSkeb](r.Data)
-	default:
+	rd, err := decodeIndex(r.Header.IndexId, r.decodeInput())
+	switch {
+	case err != nil:
+		return r.decodeFailed(fmt.Errorf("failed to decode data: %w", err))
+	case rd == nil:
 		return &db.ResultDataUnknown{Raw: r.Data}
 	}
+	return rd
+}
+
+// decodeInput 有原始 data 时重新按 [json.Number] 读取, 否则使用 Data
+func (r Result) decodeInput() map[string]any {
+	if len(r.rawData) == 0 {
+		return r.Data
+	}
+	var input map[string]any
+	if unmarshalJson(r.rawData, &input) != nil {
+		return r.Data
+	}
+	return input
+}
+
+func (r Result) decodeFailed(err error) db.ResultData {
+	r.client.logger().Warn("decode failed", LOG_INDEX_ID, int(r.Header.IndexId), LOG_ERROR, err)
+	r.client.hooks().decodeError(DecodeErrorEvent{IndexId: r.Header.IndexId, Data: r.Data, Err: err})
+	return &db.ResultDataUnknown{
+		Raw: r.Data,
+		Err: err,
+	}
 }
@@ -0,0 +1,147 @@
+package SauceNao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrNotLoggedIn = errors.New("user.php returned login page, set Client.UserCookies")
+
+// Account 为 user.php 中的账户与用量信息, 页面未给出的字段为零值
+type Account struct {
+	Username     string `json:"username"`
+	AccountType  string `json:"account_type"`   // "Basic" | "Enhanced" ...
+	ApiKey       string `json:"-"`              // 页面上显示的 api key, 不序列化, 输出时用 [Account.MaskedApiKey]
+	ApiKeyStatus string `json:"api_key_status"` // 页面上有 key 为 "active", 否则为 "none"
+	ShortLimit   int    `json:"short_limit"`    // 30s 内的搜索上限
+	LongLimit    int    `json:"long_limit"`     // 24h 内的搜索上限
+	ShortUsage   int    `json:"short_usage"`    // 当前 30s 窗口内已用
+	LongUsage    int    `json:"long_usage"`     // 当前 24h 窗口内已用
+}
+
+// MaskedApiKey 只保留 api key 的后 4 位
+func (a *Account) MaskedApiKey() string {
+	if len(a.ApiKey) <= 4 {
+		return strings.Repeat("*", len(a.ApiKey))
+	}
+	return strings.Repeat("*", len(a.ApiKey)-4) + a.ApiKey[len(a.ApiKey)-4:]
+}
+
+// String 用于日志, api key 已遮盖
+func (a Account) String() string {
+	return fmt.Sprintf("%s (%s) api key %s %s, 30s %d/%d, 24h %d/%d",
+		a.Username, a.AccountType, a.MaskedApiKey(), a.ApiKeyStatus,
+		a.ShortUsage, a.ShortLimit, a.LongUsage, a.LongLimit)
+}
+
+// LongRemaining 24h 内剩余搜索次数
+func (a *Account) LongRemaining() int {
+	return max(a.LongLimit-a.LongUsage, 0)
+}
+
+// ShortRemaining 30s 内剩余搜索次数
+func (a *Account) ShortRemaining() int {
+	return max(a.ShortLimit-a.ShortUsage, 0)
+}
+
+// Account 经 FlareSolverr 访问 user.php 并解析账户信息, 不消耗搜索次数.
+// 需要通过 [Client.UserCookies] 提供登录后的 cookies
+func (c *Client) Account(ctx context.Context) (*Account, error) {
+	body, err := c.fsGet(ctx, c.Host+USER_PATH)
+	if err != nil {
+		return nil, err
+	}
+	return parseAccount(body)
+}
+
+var (
+	reAccountApiKeyInput = regexp.MustCompile(`(?is)api[ _-]?key.{0,200}?<input[^>]*\svalue="([^"]+)"`)
+	reAccountBlock       = regexp.MustCompile(`(?i)<br\s*/?>|</(?:p|div|tr|li|h\d|table)>`)
+	reAccountCell        = regexp.MustCompile(`(?i)</t[dh]>`)
+	reAccountTag         = regexp.MustCompile(`(?s)<[^>]*>`)
+	reAccountField       = regexp.MustCompile(`^\s*([^:]{1,40}?)\s*:\s*(.+?)\s*$`)
+	reAccountNumbers     = regexp.MustCompile(`\d[\d,]*`)
+)
+
+// accountLabels 页面字段名 (小写) 到 [Account] 字段的映射, 与 testdata/user.html 一致
+var accountLabels = map[string]string{
+	"username":        "username",
+	"account type":    "account_type",
+	"30 second limit": "short_limit",
+	"30 second usage": "short_usage",
+	"daily limit":     "long_limit",
+	"daily usage":     "long_usage",
+}
+
+func parseAccount(page string) (*Account, error) {
+	if strings.Contains(page, `type="password"`) {
+		return nil, ErrNotLoggedIn
+	}
+	a := &Account{}
+	if m := reAccountApiKeyInput.FindStringSubmatch(page); m != nil {
+		a.ApiKey = html.UnescapeString(m[1])
+	}
+
+	text := reAccountBlock.ReplaceAllString(page, "\n")
+	text = reAccountCell.ReplaceAllString(text, " ")
+	text = html.UnescapeString(reAccountTag.ReplaceAllString(text, ""))
+	found := false
+	for line := range strings.SplitSeq(text, "\n") {
+		m := reAccountField.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		field, ok := accountLabels[strings.ToLower(m[1])]
+		if !ok {
+			continue
+		}
+		found = true
+		value := m[2]
+		switch field {
+		case "username":
+			a.Username = value
+		case "account_type":
+			a.AccountType = value
+		case "short_limit":
+			a.ShortLimit = accountNumbers(value)[0]
+		case "long_limit":
+			a.LongLimit = accountNumbers(value)[0]
+		case "short_usage", "long_usage":
+			// "12" 或 "12 / 100"
+			nums := accountNumbers(value)
+			if field == "short_usage" {
+				a.ShortUsage = nums[0]
+				if a.ShortLimit == 0 {
+					a.ShortLimit = nums[1]
+				}
+			} else {
+				a.LongUsage = nums[0]
+				if a.LongLimit == 0 {
+					a.LongLimit = nums[1]
+				}
+			}
+		}
+	}
+	if !found && a.ApiKey == "" {
+		return nil, ErrHtmlUnrecognized
+	}
+	a.ApiKeyStatus = "none"
+	if a.ApiKey != "" {
+		a.ApiKeyStatus = "active"
+	}
+	return a, nil
+}
+
+// accountNumbers 提取前两个整数, 不足的补 0
+func accountNumbers(s string) [2]int {
+	var nums [2]int
+	for i, m := range reAccountNumbers.FindAllString(s, 2) {
+		nums[i], _ = strconv.Atoi(strings.ReplaceAll(m, ",", ""))
+	}
+	return nums
+}
@@ -0,0 +1,57 @@
+package SauceNao
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testdata/user.html 尚未与抓取的页面核对, 有真实页面后应替换, 并同步 accountLabels 与 saucenaotest.USER_PAGE
+func TestParseAccount(t *testing.T) {
+	page, err := os.ReadFile("testdata/user.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := parseAccount(string(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Account{
+		Username:     "some_user",
+		AccountType:  "Basic",
+		ApiKey:       "0123456789abcdef0123456789abcdef01234567",
+		ApiKeyStatus: "active",
+		ShortLimit:   4,
+		ShortUsage:   1,
+		LongLimit:    1000,
+		LongUsage:    15,
+	}
+	if *a != want {
+		t.Errorf("parseAccount() = %+v, want %+v", *a, want)
+	}
+	if a.LongRemaining() != 985 {
+		t.Errorf("LongRemaining() = %d, want 985", a.LongRemaining())
+	}
+
+	// 日志与 json 中不出现完整的 key
+	j, _ := json.Marshal(a)
+	for _, out := range []string{fmt.Sprint(a), fmt.Sprintf("%+v", *a), string(j)} {
+		if strings.Contains(out, want.ApiKey) {
+			t.Errorf("api key leaked: %s", out)
+		}
+	}
+	if a.MaskedApiKey() != strings.Repeat("*", 36)+"4567" {
+		t.Errorf("MaskedApiKey() = %s", a.MaskedApiKey())
+	}
+
+	page, err = os.ReadFile("testdata/user_login.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = parseAccount(string(page))
+	if err != ErrNotLoggedIn {
+		t.Errorf("err = %v, want %v", err, ErrNotLoggedIn)
+	}
+}
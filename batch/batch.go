@@ -0,0 +1,339 @@
+// Package batch 遍历目录批量搜图, 进度写入状态文件, 中断或当日次数用尽后可续跑
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+const (
+	STATE_FILE    = ".saucenao-batch.json"
+	MANIFEST_FILE = "saucenao-manifest.json"
+)
+
+const (
+	STATUS_DONE   = "done"
+	STATUS_FAILED = "failed"
+)
+
+var DefaultExtensions = []string{".jpg", ".jpeg", ".png", ".gif", ".webp", ".bmp"}
+
+type Runner struct {
+	Client       *SauceNao.Client
+	Root         string
+	StatePath    string            // 默认为 Root 下的 [STATE_FILE]
+	ManifestPath string            // 默认为 Root 下的 [MANIFEST_FILE]
+	TopN         int               // 每个文件保留的结果数, 默认 3
+	MaxAttempts  int               // 失败文件的最大尝试次数, 默认 3
+	Extensions   []string          // 小写且带点, 默认 [DefaultExtensions]
+	Options      []SauceNao.Option // 传给 [SauceNao.Client.Search]
+	Progress     func(Progress)    // 每处理完一个文件回调一次
+	state        *State
+	manifest     Manifest
+}
+
+// State 续跑所需的进度, key 为相对 Root 的路径 (以 "/" 分隔)
+type State struct {
+	Files     map[string]*FileState `json:"files"`
+	UpdatedAt time.Time             `json:"updated_at"`
+}
+
+type FileState struct {
+	Status   string    `json:"status"` // [STATUS_DONE] | [STATUS_FAILED]
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error,omitempty"`
+	Size     int64     `json:"size"` // 与 ModTime 一起判断文件是否被替换, 变化时重新处理
+	ModTime  time.Time `json:"mod_time"`
+}
+
+// Manifest 文件到最佳结果的映射, key 同 [State.Files]
+type Manifest map[string][]ManifestEntry
+
+type ManifestEntry struct {
+	IndexId    db.IndexId      `json:"index_id"`
+	IndexName  string          `json:"index_name"`
+	Similarity float64         `json:"similarity"`
+	Urls       []string        `json:"urls"`
+	Data       json.RawMessage `json:"data"`
+}
+
+type Progress struct {
+	Path    string // 相对 Root
+	Done    int    // 已完成 (含此前运行)
+	Total   int
+	Results int // 本文件写入 manifest 的结果数
+	Err     error
+}
+
+type Summary struct {
+	Total     int // 目录下的图片数
+	Processed int // 本次成功处理
+	Failed    int // 本次失败
+	Skipped   int // 此前已完成或失败次数已达上限
+}
+
+func (r *Runner) defaults() {
+	if r.StatePath == "" {
+		r.StatePath = filepath.Join(r.Root, STATE_FILE)
+	}
+	if r.ManifestPath == "" {
+		r.ManifestPath = filepath.Join(r.Root, MANIFEST_FILE)
+	}
+	if r.TopN <= 0 {
+		r.TopN = 3
+	}
+	if r.MaxAttempts <= 0 {
+		r.MaxAttempts = 3
+	}
+	if len(r.Extensions) == 0 {
+		r.Extensions = DefaultExtensions
+	}
+}
+
+// Run 处理 Root 下所有未完成的图片, 每处理一个文件就写一次状态文件与 manifest.
+// 当日次数用尽时保存进度并返回 [SauceNao.ErrLongLimit], 之后再次调用即可续跑
+func (r *Runner) Run(ctx context.Context) (*Summary, error) {
+	r.defaults()
+	err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	files, err := r.walk()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := &Summary{Total: len(files)}
+	done := 0
+	for _, f := range files {
+		st, ok := r.state.Files[f]
+		if ok && r.changed(f, st) {
+			// 上次处理后文件被替换, 旧结果作废
+			delete(r.state.Files, f)
+			delete(r.manifest, f)
+			continue
+		}
+		if ok && st.Status == STATUS_DONE {
+			done++
+		}
+	}
+
+	for _, f := range files {
+		st := r.state.Files[f]
+		if st != nil && (st.Status == STATUS_DONE || st.Attempts >= r.MaxAttempts) {
+			sum.Skipped++
+			continue
+		}
+
+		entries, err := r.process(ctx, f)
+		if errors.Is(err, SauceNao.ErrLongLimit) || ctx.Err() != nil {
+			if e := r.save(); e != nil {
+				return sum, errors.Join(err, e)
+			}
+			return sum, err
+		}
+
+		if st == nil {
+			st = &FileState{}
+			r.state.Files[f] = st
+		}
+		st.Attempts++
+		if info, e := os.Stat(r.abs(f)); e == nil {
+			st.Size, st.ModTime = info.Size(), info.ModTime()
+		}
+		if err != nil {
+			st.Status, st.Error = STATUS_FAILED, err.Error()
+			sum.Failed++
+		} else {
+			st.Status, st.Error = STATUS_DONE, ""
+			r.manifest[f] = entries
+			sum.Processed++
+			done++
+		}
+
+		e := r.save()
+		if e != nil {
+			return sum, e
+		}
+		if r.Progress != nil {
+			r.Progress(Progress{Path: f, Done: done, Total: len(files), Results: len(entries), Err: err})
+		}
+	}
+	return sum, nil
+}
+
+// changed 文件的大小或修改时间与状态中记录的不同, 未记录时视为未变
+func (r *Runner) changed(rel string, st *FileState) bool {
+	if st.ModTime.IsZero() {
+		return false
+	}
+	info, err := os.Stat(r.abs(rel))
+	if err != nil {
+		return false
+	}
+	return info.Size() != st.Size || !info.ModTime().Equal(st.ModTime)
+}
+
+// process 搜索单个文件
+func (r *Runner) process(ctx context.Context, rel string) ([]ManifestEntry, error) {
+	resp, err := Search(ctx, r.Client, r.abs(rel), r.Options...)
+	if err != nil {
+		return nil, err
+	}
+	return Entries(resp, r.TopN), nil
+}
+
+// Search 30s 限额用尽时先等待窗口结束, 再搜索一次.
+// 返回 429 时不重试, 当日次数已用尽则返回 [SauceNao.ErrLongLimit],
+// 否则原样返回, 由调用方计入失败次数, 以免一直卡在同一个文件
+func Search(ctx context.Context, c *SauceNao.Client, image any, opts ...SauceNao.Option) (*SauceNao.Response, error) {
+	err := c.WaitQuota(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Search(ctx, image, opts...)
+	if SauceNao.IsRateLimited(err) && c.Quota().LongRemaining <= 0 {
+		return nil, SauceNao.ErrLongLimit
+	}
+	return resp, err
+}
+
+// Entries 按 [SauceNao.Response.Rank] 取前 n 个不低于最低相似度的结果
+func Entries(resp *SauceNao.Response, n int) []ManifestEntry {
+	entries := []ManifestEntry{}
+	for _, rr := range resp.Rank(nil) {
+		if len(entries) >= n {
+			break
+		}
+		if resp.Confidence(rr.Result, nil) == SauceNao.CONFIDENCE_BELOW_MINIMUM {
+			continue
+		}
+		data := rr.DecodeData()
+		var urls []string
+		for _, u := range data.CanonicalURLs() {
+			urls = append(urls, u.String())
+		}
+		entries = append(entries, ManifestEntry{
+			IndexId:    rr.Header.IndexId,
+			IndexName:  rr.Header.IndexId.String(),
+			Similarity: rr.Header.SimilarityFloat(),
+			Urls:       urls,
+			Data:       json.RawMessage(data.Json("")),
+		})
+	}
+	return entries
+}
+
+// Result 还原为 [SauceNao.Result], 用于重命名等需要结果数据的场合
+func (e ManifestEntry) Result() SauceNao.Result {
+	r := SauceNao.Result{
+		Header: SauceNao.ResultHeader{
+			Similarity: strconv.FormatFloat(e.Similarity, 'f', 2, 64),
+			IndexId:    e.IndexId,
+		},
+	}
+	json.Unmarshal(e.Data, &r.Data)
+	return r
+}
+
+func (r *Runner) abs(rel string) string {
+	return filepath.Join(r.Root, filepath.FromSlash(rel))
+}
+
+// walk 返回 Root 下所有图片的相对路径, 按字典序
+func (r *Runner) walk() ([]string, error) {
+	stateAbs, _ := filepath.Abs(r.StatePath)
+	manifestAbs, _ := filepath.Abs(r.ManifestPath)
+	var files []string
+	err := filepath.WalkDir(r.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != r.Root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !slices.Contains(r.Extensions, strings.ToLower(filepath.Ext(path))) {
+			return nil
+		}
+		if abs, _ := filepath.Abs(path); abs == stateAbs || abs == manifestAbs {
+			return nil
+		}
+		rel, err := filepath.Rel(r.Root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	slices.Sort(files)
+	return files, err
+}
+
+func (r *Runner) load() error {
+	r.state = &State{Files: map[string]*FileState{}}
+	r.manifest = Manifest{}
+	err := readJson(r.StatePath, r.state)
+	if err != nil {
+		return err
+	}
+	if r.state.Files == nil {
+		r.state.Files = map[string]*FileState{}
+	}
+	return readJson(r.ManifestPath, &r.manifest)
+}
+
+func (r *Runner) save() error {
+	r.state.UpdatedAt = time.Now()
+	err := WriteJsonAtomic(r.StatePath, r.state)
+	if err != nil {
+		return err
+	}
+	return WriteJsonAtomic(r.ManifestPath, r.manifest)
+}
+
+// readJson 文件不存在时不做任何事
+func readJson(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// WriteJsonAtomic 先写临时文件再重命名, 避免崩溃时留下不完整的文件
+func WriteJsonAtomic(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if e := tmp.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
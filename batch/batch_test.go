@@ -0,0 +1,155 @@
+package batch
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+)
+
+func TestRunResume(t *testing.T) {
+	longRemaining := 2
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if longRemaining <= 0 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"header":{"status":-2,"message":"Daily Search Limit Exceeded."}}`)
+			return
+		}
+		longRemaining--
+		fmt.Fprintf(w, `{"header":{"short_limit":"4","long_limit":"100","short_remaining":3,"long_remaining":%d,"minimum_similarity":50},
+"results":[{"header":{"similarity":"90.00","index_id":5},"data":{"pixiv_id":1,"member_id":2,"title":"t"}}]}`, longRemaining)
+	}))
+	defer srv.Close()
+
+	root := t.TempDir()
+	for _, name := range []string{"a.png", "b.jpg", "sub/c.webp", "notes.txt"} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0o755)
+		os.WriteFile(p, []byte(name), 0o644)
+	}
+
+	runner := &Runner{Client: SauceNao.NewClient("key", srv.URL, 0, SauceNao.HIDE_NONE, nil), Root: root}
+	sum, err := runner.Run(t.Context())
+	if err != SauceNao.ErrLongLimit {
+		t.Fatalf("err = %v, want %v", err, SauceNao.ErrLongLimit)
+	}
+	if sum.Total != 3 || sum.Processed != 2 {
+		t.Fatalf("summary = %+v", sum)
+	}
+
+	// 次日额度恢复后续跑
+	longRemaining = 10
+	runner = &Runner{Client: SauceNao.NewClient("key", srv.URL, 0, SauceNao.HIDE_NONE, nil), Root: root}
+	sum, err = runner.Run(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Processed != 1 || sum.Skipped != 2 {
+		t.Fatalf("summary = %+v", sum)
+	}
+
+	manifest := Manifest{}
+	err = readJson(filepath.Join(root, MANIFEST_FILE), &manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest) != 3 || len(manifest["sub/c.webp"]) != 1 || manifest["sub/c.webp"][0].Urls[0] != "https://www.pixiv.net/artworks/1" {
+		t.Errorf("manifest = %+v", manifest)
+	}
+
+	// 替换过的文件重新处理, 尝试次数重新计算
+	a := filepath.Join(root, "a.png")
+	os.WriteFile(a, []byte("replaced image"), 0o644)
+	os.Chtimes(a, time.Now(), time.Now().Add(time.Hour))
+	runner = &Runner{Client: SauceNao.NewClient("key", srv.URL, 0, SauceNao.HIDE_NONE, nil), Root: root}
+	sum, err = runner.Run(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Processed != 1 || sum.Skipped != 2 {
+		t.Fatalf("after replace: summary = %+v", sum)
+	}
+	state := State{}
+	err = readJson(filepath.Join(root, STATE_FILE), &state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := state.Files["a.png"]; st.Attempts != 1 || st.Size != int64(len("replaced image")) {
+		t.Errorf("a.png state = %+v", st)
+	}
+}
+
+// 30s 限额的 429 计入尝试次数, 不会一直重试同一个文件
+func TestRunRateLimitedCountsAttempt(t *testing.T) {
+	searches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		searches++
+		if searches > 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"header":{"status":-2,"message":"Search Rate Too High."}}`)
+			return
+		}
+		fmt.Fprint(w, `{"header":{"short_limit":"4","long_limit":"100","short_remaining":3,"long_remaining":90,"minimum_similarity":50},"results":[]}`)
+	}))
+	defer srv.Close()
+
+	root := t.TempDir()
+	for _, name := range []string{"a.png", "b.png"} {
+		os.WriteFile(filepath.Join(root, name), []byte(name), 0o644)
+	}
+	runner := &Runner{Client: SauceNao.NewClient("key", srv.URL, 0, SauceNao.HIDE_NONE, nil), Root: root, MaxAttempts: 1}
+	sum, err := runner.Run(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Processed != 1 || sum.Failed != 1 || searches != 2 {
+		t.Fatalf("summary = %+v, searches = %d", sum, searches)
+	}
+
+	runner = &Runner{Client: SauceNao.NewClient("key", srv.URL, 0, SauceNao.HIDE_NONE, nil), Root: root, MaxAttempts: 1}
+	sum, err = runner.Run(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Skipped != 2 || searches != 2 {
+		t.Errorf("after max attempts: summary = %+v, searches = %d", sum, searches)
+	}
+}
+
+// 当日次数用尽的 429 不计入尝试次数
+func TestRunDailyLimitNotCounted(t *testing.T) {
+	searches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		searches++
+		if searches > 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"header":{"status":-2,"message":"Daily Search Limit Exceeded."}}`)
+			return
+		}
+		fmt.Fprint(w, `{"header":{"short_limit":"4","long_limit":"100","short_remaining":3,"long_remaining":90,"minimum_similarity":50},"results":[]}`)
+	}))
+	defer srv.Close()
+
+	root := t.TempDir()
+	for _, name := range []string{"a.png", "b.png"} {
+		os.WriteFile(filepath.Join(root, name), []byte(name), 0o644)
+	}
+	runner := &Runner{Client: SauceNao.NewClient("key", srv.URL, 0, SauceNao.HIDE_NONE, nil), Root: root}
+	_, err := runner.Run(t.Context())
+	if err != SauceNao.ErrLongLimit {
+		t.Fatalf("err = %v, want %v", err, SauceNao.ErrLongLimit)
+	}
+	state := State{}
+	err = readJson(filepath.Join(root, STATE_FILE), &state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := state.Files["b.png"]; st != nil {
+		t.Errorf("b.png state = %+v", st)
+	}
+}
@@ -0,0 +1,144 @@
+package SauceNao
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache 缓存搜索结果, 同一图片 (按内容) 或链接在相同参数下只请求一次.
+// 返回的 *Response 由多个调用方共享, 不应修改
+type Cache interface {
+	Get(key string) (*Response, bool)
+	Set(key string, resp *Response)
+}
+
+// key 包含影响结果的参数
+func (ro *requestOptions) cacheKey(kind, input string) string {
+	return fmt.Sprintf("%s:%s:%d:%d:%d:%t:%t:%t", kind, input, ro.numRes, ro.dbMask, ro.hide, ro.safeFilter, ro.html, ro.rawBody)
+}
+
+func imageCacheKey(ro *requestOptions, imgData []byte) string {
+	sum := sha256.Sum256(imgData)
+	return ro.cacheKey("sha256", hex.EncodeToString(sum[:]))
+}
+
+func urlCacheKey(ro *requestOptions, imgUrl string) string {
+	return ro.cacheKey("url", imgUrl)
+}
+
+// MemoryCache 进程内的 LRU 缓存, 并发安全
+type MemoryCache struct {
+	Size int           // 最多条目数, <= 0 不限制
+	TTL  time.Duration // <= 0 不过期
+
+	mu    sync.Mutex
+	lru   *list.List // front 为最近使用
+	items map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key     string
+	resp    *Response
+	expires time.Time
+}
+
+func NewMemoryCache(size int, ttl time.Duration) *MemoryCache {
+	return &MemoryCache{Size: size, TTL: ttl}
+}
+
+func (mc *MemoryCache) Get(key string) (*Response, bool) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	e, ok := mc.items[key]
+	if !ok {
+		return nil, false
+	}
+	ce := e.Value.(*cacheEntry)
+	if !ce.expires.IsZero() && time.Now().After(ce.expires) {
+		mc.lru.Remove(e)
+		delete(mc.items, key)
+		return nil, false
+	}
+	mc.lru.MoveToFront(e)
+	return ce.resp, true
+}
+
+func (mc *MemoryCache) Set(key string, resp *Response) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.items == nil {
+		mc.lru = list.New()
+		mc.items = map[string]*list.Element{}
+	}
+	ce := &cacheEntry{key: key, resp: resp}
+	if mc.TTL > 0 {
+		ce.expires = time.Now().Add(mc.TTL)
+	}
+	if e, ok := mc.items[key]; ok {
+		e.Value = ce
+		mc.lru.MoveToFront(e)
+		return
+	}
+	mc.items[key] = mc.lru.PushFront(ce)
+	for mc.Size > 0 && mc.lru.Len() > mc.Size {
+		last := mc.lru.Back()
+		mc.lru.Remove(last)
+		delete(mc.items, last.Value.(*cacheEntry).key)
+	}
+}
+
+// Len 当前条目数 (含已过期未清理的)
+func (mc *MemoryCache) Len() int {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return len(mc.items)
+}
+
+// CachedSearch 只查缓存, 不发出请求, key 与 [Client.Search] 相同.
+// 仅支持 []byte 与图片链接, 其他类型读取后无法再用于搜索, 总是返回 false
+func (c *Client) CachedSearch(image any, opts ...Option) (*Response, bool) {
+	if c.Cache == nil {
+		return nil, false
+	}
+	ro := c.requestOptions(opts)
+	var key string
+	switch img := image.(type) {
+	case []byte:
+		key = imageCacheKey(ro, img)
+	case string:
+		if !strings.HasPrefix(img, "http") {
+			return nil, false
+		}
+		key = urlCacheKey(ro, img)
+	default:
+		return nil, false
+	}
+	resp, ok := c.Cache.Get(key)
+	if ok {
+		c.Metrics.observeCache(true)
+		c.Metrics.observeSearch(resp, nil)
+	}
+	return resp, ok
+}
+
+// cached 命中缓存时直接返回, 否则调用 fetch 并缓存成功的结果
+func (c *Client) cached(key string, fetch func() (*Response, error)) (*Response, error) {
+	if c.Cache == nil {
+		return fetch()
+	}
+	if resp, ok := c.Cache.Get(key); ok {
+		c.Metrics.observeCache(true)
+		return resp, nil
+	}
+	c.Metrics.observeCache(false)
+	resp, err := fetch()
+	if err == nil {
+		c.Cache.Set(key, resp)
+	}
+	return resp, err
+}
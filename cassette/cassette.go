@@ -0,0 +1,312 @@
+// Package cassette 录制与回放 SauceNAO 的 http 流量, 用于把真实响应保存为测试数据.
+// 录制时 api_key 与 cookies 会被隐去, 每行一个 [Interaction] (jsonl)
+//
+//	client.HttpClient = &http.Client{Transport: cassette.New("testdata/search.jsonl", cassette.MODE_REPLAY)}
+//
+// 仅作用于 [SauceNao.Client.HttpClient], 经 FlareSolverr 的请求不会被录制
+package cassette
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+)
+
+type Mode int
+
+const (
+	MODE_REPLAY Mode = iota // 只从文件返回, 没有匹配时失败
+	MODE_RECORD             // 实际发送并追加到文件
+)
+
+var ErrNoMatch = errors.New("cassette: no recorded interaction matches the request")
+
+// scrubbedParams 录制时隐去值的参数
+var scrubbedParams = []string{"api_key"}
+
+// droppedHeaders 录制时不保存的响应头 (小写比较)
+var droppedHeaders = []string{"set-cookie", "cookie", "cf-ray", "date"}
+
+// Interaction 一次请求与其响应.
+// 按 Method, Path, Params 与 BodySha256 匹配
+type Interaction struct {
+	Method     string      `json:"method"`
+	Path       string      `json:"path"`
+	Params     url.Values  `json:"params,omitempty"`      // query 与表单的非文件字段
+	BodySha256 string      `json:"body_sha256,omitempty"` // 上传文件的内容, 或无法解析为表单的请求体
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+	RecordedAt time.Time   `json:"recorded_at"`
+}
+
+type Transport struct {
+	Path string
+	Mode Mode
+	Base http.RoundTripper // 录制时实际发送请求, 为 nil 时使用 http.DefaultTransport
+
+	mu           sync.Mutex
+	loaded       bool
+	interactions []Interaction
+	used         []bool
+}
+
+func New(path string, mode Mode) *Transport {
+	return &Transport{Path: path, Mode: mode}
+}
+
+// Load 读取文件中的所有记录
+func Load(path string) ([]Interaction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var interactions []Interaction
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 64<<20)
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var in Interaction
+		err = json.Unmarshal(sc.Bytes(), &in)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		interactions = append(interactions, in)
+	}
+	return interactions, sc.Err()
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key, secrets, err := requestKey(req)
+	if err != nil {
+		return nil, err
+	}
+	if t.Mode == MODE_RECORD {
+		return t.record(req, key, secrets)
+	}
+	return t.replay(req, key)
+}
+
+// replay 优先返回未使用过的记录, 都用过时重复最后一条
+func (t *Transport) replay(req *http.Request, key Interaction) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.loaded {
+		interactions, err := Load(t.Path)
+		if err != nil {
+			return nil, err
+		}
+		t.interactions, t.used, t.loaded = interactions, make([]bool, len(interactions)), true
+	}
+	match := -1
+	for i, in := range t.interactions {
+		if !in.matches(key) {
+			continue
+		}
+		match = i
+		if !t.used[i] {
+			break
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("%w: %s %s %s", ErrNoMatch, key.Method, key.Path, key.Params.Encode())
+	}
+	t.used[match] = true
+	return t.interactions[match].response(req), nil
+}
+
+func (t *Transport) record(req *http.Request, key Interaction, secrets []string) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	in := key
+	in.StatusCode = resp.StatusCode
+	in.Header = scrubHeader(resp.Header)
+	in.Body = scrubBody(string(body), secrets)
+	in.RecordedAt = time.Now().UTC()
+	line, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, err := os.OpenFile(t.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// requestKey 提取用于匹配的字段, 已隐去 scrubbedParams 中的参数, secrets 为被隐去的值与 cookies.
+// 会读取并还原 req.Body
+func requestKey(req *http.Request) (key Interaction, secrets []string, err error) {
+	key = Interaction{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Params: req.URL.Query(),
+	}
+	for _, c := range req.Cookies() {
+		secrets = append(secrets, c.Value)
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		secrets, key.Params = scrubParams(secrets, key.Params)
+		return key, secrets, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return key, nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	h := sha256.New()
+	mediaType, params, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return key, nil, err
+		}
+		mergeParams(key.Params, form)
+	case strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "":
+		// boundary 每次不同, 只取字段与文件内容
+		mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return key, nil, err
+			}
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return key, nil, err
+			}
+			if part.FileName() == "" {
+				key.Params.Add(part.FormName(), string(data))
+				continue
+			}
+			fmt.Fprintf(h, "%s\x00%d\x00", part.FormName(), len(data))
+			h.Write(data)
+		}
+	default:
+		h.Write(body)
+	}
+	if sum := h.Sum(nil); !bytes.Equal(sum, sha256.New().Sum(nil)) {
+		key.BodySha256 = hex.EncodeToString(sum)
+	}
+	secrets, key.Params = scrubParams(secrets, key.Params)
+	return key, secrets, nil
+}
+
+func (in *Interaction) matches(key Interaction) bool {
+	if in.Method != key.Method || in.Path != key.Path || in.BodySha256 != key.BodySha256 {
+		return false
+	}
+	if len(in.Params) != len(key.Params) {
+		return false
+	}
+	for k, v := range in.Params {
+		if !slices.Equal(v, key.Params[k]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (in *Interaction) response(req *http.Request) *http.Response {
+	header := in.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.StatusCode, http.StatusText(in.StatusCode)),
+		StatusCode:    in.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(in.Body)),
+		ContentLength: int64(len(in.Body)),
+		Request:       req,
+	}
+}
+
+func mergeParams(dst, src url.Values) {
+	for k, v := range src {
+		dst[k] = append(dst[k], v...)
+	}
+}
+
+// scrubParams 被隐去的值追加到 secrets
+func scrubParams(secrets []string, params url.Values) ([]string, url.Values) {
+	for _, k := range scrubbedParams {
+		if params.Has(k) {
+			secrets = append(secrets, params[k]...)
+			params.Set(k, SauceNao.REDACTED)
+		}
+	}
+	if len(params) == 0 {
+		return secrets, nil
+	}
+	return secrets, params
+}
+
+func scrubHeader(h http.Header) http.Header {
+	out := http.Header{}
+	for k, v := range h {
+		if slices.Contains(droppedHeaders, strings.ToLower(k)) {
+			continue
+		}
+		out[k] = slices.Clone(v)
+	}
+	return out
+}
+
+// scrubBody 隐去响应中出现的 secrets (如 user.php 页面上的 api key), 过短的值不处理以免误伤
+func scrubBody(body string, secrets []string) string {
+	for _, s := range secrets {
+		if len(s) >= 8 {
+			body = strings.ReplaceAll(body, s, SauceNao.REDACTED)
+		}
+	}
+	return body
+}
@@ -0,0 +1,78 @@
+package cassette
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+	"github.com/Miuzarte/SauceNAO-go/db"
+	"github.com/Miuzarte/SauceNAO-go/saucenaotest"
+)
+
+func TestRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "search.jsonl")
+	srv := saucenaotest.NewServer()
+	ctx := t.Context()
+
+	c := srv.Client()
+	c.HttpClient = &http.Client{Transport: New(path, MODE_RECORD)}
+	c.KeepRawBody = true
+	want, err := c.Post(ctx, []byte("image a"), SauceNao.WithNumRes(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Get(ctx, "https://example.com/a.png", SauceNao.WithDbMask(db.Mask(db.DANBOORU))); err != nil {
+		t.Fatal(err)
+	}
+	srv.SetRemaining(0, 10)
+	if _, err = c.Post(ctx, []byte("image a"), SauceNao.WithNumRes(2)); !SauceNao.IsRateLimited(err) {
+		t.Fatalf("expected 429, got %v", err)
+	}
+	srv.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 3 {
+		t.Errorf("recorded %d interactions", n)
+	}
+	if strings.Contains(string(data), saucenaotest.API_KEY) {
+		t.Error("api key was not scrubbed")
+	}
+
+	c = SauceNao.NewClient("another-key", srv.URL, 0, SauceNao.HIDE_NONE, nil)
+	c.HttpClient = &http.Client{Transport: New(path, MODE_REPLAY)}
+	c.KeepRawBody = true
+	got, err := c.Post(ctx, []byte("image a"), SauceNao.WithNumRes(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.RawBody != want.RawBody || len(got.Results) != 2 {
+		t.Errorf("replayed %+v", got.Header)
+	}
+	// 两条记录都已用过后重复最后一条 (429)
+	if _, err = c.Post(ctx, []byte("image a"), SauceNao.WithNumRes(2)); !SauceNao.IsRateLimited(err) {
+		t.Errorf("second replay: %v", err)
+	}
+	if _, err = c.Post(ctx, []byte("image a"), SauceNao.WithNumRes(2)); !SauceNao.IsRateLimited(err) {
+		t.Errorf("third replay: %v", err)
+	}
+	got, err = c.Get(ctx, "https://example.com/a.png", SauceNao.WithDbMask(db.Mask(db.DANBOORU)))
+	if err != nil || len(got.Results) != 1 || got.Results[0].Header.IndexId != db.DANBOORU {
+		t.Errorf("replayed get: %v", err)
+	}
+
+	// 图片内容不同
+	if _, err = c.Post(ctx, []byte("image b"), SauceNao.WithNumRes(2)); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("different body: %v", err)
+	}
+	// 参数不同
+	if _, err = c.Get(ctx, "https://example.com/a.png"); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("different params: %v", err)
+	}
+}
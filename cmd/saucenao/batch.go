@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+	"github.com/Miuzarte/SauceNAO-go/batch"
+)
+
+func cmdBatch(args []string) int {
+	cfg, err := loadConfig(args)
+	if err != nil {
+		errorf("%v", err)
+		return EXIT_ERROR
+	}
+	fset := flag.NewFlagSet("batch", flag.ContinueOnError)
+	clientFlags(fset, cfg)
+	statePath := fset.String("state", "", "state file (default <dir>/"+batch.STATE_FILE+")")
+	manifestPath := fset.String("manifest", "", "results manifest (default <dir>/"+batch.MANIFEST_FILE+")")
+	topN := fset.Int("top", 3, "results kept per file")
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "usage: saucenao batch [flags] <dir>")
+		fset.PrintDefaults()
+	}
+	if err := fset.Parse(args); err != nil {
+		return parseExitCode(err)
+	}
+	if fset.NArg() != 1 {
+		fset.Usage()
+		return EXIT_ERROR
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		errorf("%v", err)
+		return EXIT_ERROR
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	runner := &batch.Runner{
+		Client:       client,
+		Root:         fset.Arg(0),
+		StatePath:    *statePath,
+		ManifestPath: *manifestPath,
+		TopN:         *topN,
+		Progress: func(p batch.Progress) {
+			if p.Err != nil {
+				errorf("[%d/%d] %s: %v", p.Done, p.Total, p.Path, p.Err)
+			} else {
+				fmt.Fprintf(os.Stderr, "[%d/%d] %s: %d results\n", p.Done, p.Total, p.Path, p.Results)
+			}
+		},
+	}
+	sum, err := runner.Run(ctx)
+	if sum != nil {
+		fmt.Fprintf(os.Stderr, "total %d, processed %d, failed %d, skipped %d\n",
+			sum.Total, sum.Processed, sum.Failed, sum.Skipped)
+	}
+	switch {
+	case errors.Is(err, SauceNao.ErrLongLimit):
+		errorf("%v, run again later to resume", err)
+		return EXIT_RATE_LIMITED
+	case err != nil:
+		errorf("%v", err)
+		return EXIT_ERROR
+	}
+	return EXIT_OK
+}
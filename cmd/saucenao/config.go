@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+	"github.com/Miuzarte/SauceNAO-go/cassette"
+	"github.com/Miuzarte/SauceNAO-go/db"
+
+	FlareSolverr "github.com/Miuzarte/FlareSolverr-go"
+)
+
+// Config 优先级: 命令行参数 > 环境变量 > 配置文件
+type Config struct {
+	ApiKey       string `json:"api_key"`
+	Host         string `json:"host"`
+	NumRes       int    `json:"numres"`
+	Hide         int    `json:"hide"`
+	DbMask       string `json:"dbmask"` // 见 [parseDbMask]
+	FlareSolverr string `json:"flaresolverr"`
+	LogLevel     string `json:"log_level"` // debug | info | warn | error, 空为不输出
+	Record       string `json:"-"`         // 把与 SauceNAO 的流量追加到此 jsonl 文件, 见 [cassette]
+	Replay       string `json:"-"`         // 从此 jsonl 文件回放, 不发出请求
+}
+
+const (
+	ENV_CONFIG       = "SAUCENAO_CONFIG"
+	ENV_API_KEY      = "SAUCENAO_API_KEY"
+	ENV_HOST         = "SAUCENAO_HOST"
+	ENV_NUMRES       = "SAUCENAO_NUMRES"
+	ENV_HIDE         = "SAUCENAO_HIDE"
+	ENV_DBMASK       = "SAUCENAO_DBMASK"
+	ENV_FLARESOLVERR = "SAUCENAO_FLARESOLVERR"
+)
+
+// defaultConfigPath $SAUCENAO_CONFIG 或 {UserConfigDir}/saucenao/config.json
+func defaultConfigPath() string {
+	if p := os.Getenv(ENV_CONFIG); p != "" {
+		return p
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "saucenao", "config.json")
+}
+
+// configPathFromArgs 在解析参数前找出 -config, 以便配置文件的值作为其他参数的默认值
+func configPathFromArgs(args []string) (path string, explicit bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value, true
+		}
+		if i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return defaultConfigPath(), false
+}
+
+func loadConfig(args []string) (*Config, error) {
+	cfg := &Config{}
+	path, explicit := configPathFromArgs(args)
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			err = json.Unmarshal(data, cfg)
+			if err != nil {
+				return nil, fmt.Errorf("config %s: %w", path, err)
+			}
+		case explicit || !errors.Is(err, fs.ErrNotExist):
+			return nil, err
+		}
+	}
+
+	if v := os.Getenv(ENV_API_KEY); v != "" {
+		cfg.ApiKey = v
+	}
+	if v := os.Getenv(ENV_HOST); v != "" {
+		cfg.Host = v
+	}
+	if v := os.Getenv(ENV_NUMRES); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ENV_NUMRES, err)
+		}
+		cfg.NumRes = n
+	}
+	if v := os.Getenv(ENV_HIDE); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ENV_HIDE, err)
+		}
+		cfg.Hide = n
+	}
+	if v := os.Getenv(ENV_DBMASK); v != "" {
+		cfg.DbMask = v
+	}
+	if v := os.Getenv(ENV_FLARESOLVERR); v != "" {
+		cfg.FlareSolverr = v
+	}
+	return cfg, nil
+}
+
+// clientFlags 注册与 Client 相关的公共参数, 默认值取自 cfg
+func clientFlags(fset *flag.FlagSet, cfg *Config) {
+	fset.String("config", "", "config file (default $"+ENV_CONFIG+" or "+defaultConfigPath()+")")
+	fset.StringVar(&cfg.ApiKey, "key", cfg.ApiKey, "api key ($"+ENV_API_KEY+")")
+	fset.StringVar(&cfg.Host, "host", cfg.Host, "override api host ($"+ENV_HOST+")")
+	fset.IntVar(&cfg.NumRes, "numres", cfg.NumRes, "number of results ($"+ENV_NUMRES+")")
+	fset.IntVar(&cfg.Hide, "hide", cfg.Hide, "hide level 0-3: none, explicit, suspected, all but safe ($"+ENV_HIDE+")")
+	fset.StringVar(&cfg.DbMask, "dbmask", cfg.DbMask, "index ids separated by commas, or a numeric mask like 0x20 ($"+ENV_DBMASK+")")
+	fset.StringVar(&cfg.FlareSolverr, "fs", cfg.FlareSolverr, "FlareSolverr endpoint, e.g. http://127.0.0.1:8191/v1 ($"+ENV_FLARESOLVERR+")")
+	fset.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "log to stderr at debug, info, warn or error; off if empty")
+	fset.StringVar(&cfg.Record, "record", cfg.Record, "append SauceNAO traffic to a jsonl cassette, api key and cookies scrubbed")
+	fset.StringVar(&cfg.Replay, "replay", cfg.Replay, "serve SauceNAO responses from a jsonl cassette instead of the network")
+}
+
+func newClient(cfg *Config) (*SauceNao.Client, error) {
+	if cfg.Hide < int(SauceNao.HIDE_NONE) || cfg.Hide > int(SauceNao.HIDE_ALL_BUT_SAFE) {
+		return nil, fmt.Errorf("invalid hide level %d", cfg.Hide)
+	}
+	mask, err := parseDbMask(cfg.DbMask)
+	if err != nil {
+		return nil, err
+	}
+	var fsClient *FlareSolverr.Client
+	if cfg.FlareSolverr != "" {
+		fsClient = FlareSolverr.NewClient(cfg.FlareSolverr)
+	}
+	client := SauceNao.NewClient(cfg.ApiKey, cfg.Host, cfg.NumRes, SauceNao.HideLevel(cfg.Hide), fsClient)
+	client.DbMask = mask
+	client.HtmlFallback = true
+	if cfg.LogLevel != "" {
+		var level slog.Level
+		err = level.UnmarshalText([]byte(cfg.LogLevel))
+		if err != nil {
+			return nil, fmt.Errorf("log level: %w", err)
+		}
+		client.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	}
+	switch {
+	case cfg.Record != "" && cfg.Replay != "":
+		return nil, errors.New("-record and -replay are mutually exclusive")
+	case cfg.Record != "":
+		client.HttpClient = &http.Client{Transport: cassette.New(cfg.Record, cassette.MODE_RECORD)}
+	case cfg.Replay != "":
+		client.HttpClient = &http.Client{Transport: cassette.New(cfg.Replay, cassette.MODE_REPLAY)}
+	}
+	return client, nil
+}
+
+// parseDbMask 索引 id 列表 "5,9,41", 或带前缀的数值掩码 "0x20000220"
+func parseDbMask(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if !strings.Contains(s, ",") && (strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0b")) {
+		return strconv.ParseUint(s, 0, 64)
+	}
+	var ids []db.IndexId
+	for part := range strings.SplitSeq(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 0 || n >= 64 {
+			return 0, fmt.Errorf("invalid dbmask %q", s)
+		}
+		ids = append(ids, db.IndexId(n))
+	}
+	return db.Mask(ids...), nil
+}
@@ -0,0 +1,60 @@
+// saucenao 命令行工具
+//
+//	saucenao [search] [flags] <file|url|->...
+//	saucenao batch [flags] <dir>
+//	saucenao watch [flags] <dir>
+//	saucenao rename [flags] <file|dir>...
+//	saucenao serve [flags]
+//	saucenao schema [flags] <file|dir>...
+//
+// 配置依次取自配置文件、环境变量与命令行参数, 见 [Config]
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+const (
+	EXIT_OK           = 0
+	EXIT_ERROR        = 1
+	EXIT_NO_MATCH     = 2
+	EXIT_RATE_LIMITED = 3
+	EXIT_SCHEMA_DRIFT = 4
+)
+
+var commands = map[string]func(args []string) int{
+	"search": cmdSearch,
+	"batch":  cmdBatch,
+	"watch":  cmdWatch,
+	"rename": cmdRename,
+	"serve":  cmdServe,
+	"schema": cmdSchema,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) > 0 {
+		if cmd, ok := commands[args[0]]; ok {
+			return cmd(args[1:])
+		}
+	}
+	return cmdSearch(args)
+}
+
+// parseExitCode -h 时正常退出
+func parseExitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return EXIT_OK
+	}
+	return EXIT_ERROR
+}
+
+func errorf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "saucenao: "+format+"\n", args...)
+}
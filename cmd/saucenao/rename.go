@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+	"github.com/Miuzarte/SauceNAO-go/batch"
+	"github.com/Miuzarte/SauceNAO-go/meta"
+	"github.com/Miuzarte/SauceNAO-go/rename"
+	"github.com/Miuzarte/SauceNAO-go/watch"
+)
+
+const DEFAULT_RENAME_TEMPLATE = "{{.Index}}_{{.Title}}"
+
+var errNoResult = errors.New("no result")
+
+// cmdRename 结果依次取自 -manifest, watch 的 sidecar, 都没有时实时搜索
+func cmdRename(args []string) int {
+	cfg, err := loadConfig(args)
+	if err != nil {
+		errorf("%v", err)
+		return EXIT_ERROR
+	}
+	fset := flag.NewFlagSet("rename", flag.ContinueOnError)
+	clientFlags(fset, cfg)
+	text := fset.String("template", DEFAULT_RENAME_TEMPLATE, "text/template for the new name, without extension")
+	dryRun := fset.Bool("n", false, "dry run, only print what would be renamed")
+	manifestPath := fset.String("manifest", "", "take results from a batch manifest instead of searching")
+	maxLen := fset.Int("max-len", rename.DEFAULT_MAX_LEN, "max bytes of the new name")
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "usage: saucenao rename [flags] <file|dir>...")
+		fset.PrintDefaults()
+	}
+	if err := fset.Parse(args); err != nil {
+		return parseExitCode(err)
+	}
+	if fset.NArg() == 0 {
+		fset.Usage()
+		return EXIT_ERROR
+	}
+	tmpl, err := rename.Parse(*text)
+	if err != nil {
+		errorf("%v", err)
+		return EXIT_ERROR
+	}
+
+	var manifest batch.Manifest
+	manifestDir := ""
+	if *manifestPath != "" {
+		data, err := os.ReadFile(*manifestPath)
+		if err == nil {
+			err = json.Unmarshal(data, &manifest)
+		}
+		if err != nil {
+			errorf("%v", err)
+			return EXIT_ERROR
+		}
+		manifestDir = filepath.Dir(*manifestPath)
+	}
+
+	files, err := collectImages(fset.Args())
+	if err != nil {
+		errorf("%v", err)
+		return EXIT_ERROR
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var client *SauceNao.Client
+	lookup := func(path string) ([]batch.ManifestEntry, error) {
+		if manifest != nil {
+			rel, err := filepath.Rel(manifestDir, path)
+			if err != nil {
+				return nil, err
+			}
+			return manifest[filepath.ToSlash(rel)], nil
+		}
+		if sc, err := watch.ReadSidecar(path); err == nil {
+			return sc.Results, nil
+		}
+		if client == nil {
+			c, err := newClient(cfg)
+			if err != nil {
+				return nil, err
+			}
+			client = c
+		}
+		err := client.WaitQuota(ctx)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Search(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		return batch.Entries(resp, 1), nil
+	}
+
+	code := EXIT_OK
+	var items []rename.Item
+	for _, path := range files {
+		entries, err := lookup(path)
+		if err == nil && len(entries) == 0 {
+			err = errNoResult
+		}
+		if err != nil {
+			errorf("%s: %v", path, err)
+			if SauceNao.IsRateLimited(err) || errors.Is(err, SauceNao.ErrLongLimit) {
+				return EXIT_RATE_LIMITED
+			}
+			if ctx.Err() != nil {
+				return EXIT_ERROR
+			}
+			code = EXIT_ERROR
+			continue
+		}
+		items = append(items, rename.Item{Path: path, Result: entries[0].Result()})
+	}
+
+	rn := &rename.Renamer{Template: tmpl, DryRun: *dryRun, MaxLen: *maxLen}
+	for _, op := range rn.Rename(items) {
+		if op.Err != nil {
+			errorf("%v", op)
+			code = EXIT_ERROR
+			continue
+		}
+		fmt.Println(op)
+		if !*dryRun && op.From != op.To {
+			// sidecar 跟随图片改名
+			for _, suffix := range []string{watch.SIDECAR_SUFFIX, meta.SIDECAR_EXT} {
+				if _, err := os.Stat(op.From + suffix); err == nil {
+					os.Rename(op.From+suffix, op.To+suffix)
+				}
+			}
+		}
+	}
+	return code
+}
+
+// collectImages 目录展开为其下所有图片, 按字典序
+func collectImages(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		var found []string
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && path != arg && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() && slices.Contains(batch.DefaultExtensions, strings.ToLower(filepath.Ext(path))) {
+				found = append(found, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		slices.Sort(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
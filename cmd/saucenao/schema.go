@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+	"github.com/Miuzarte/SauceNAO-go/cassette"
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+// cmdSchema 检查保存的响应中 data 字段与 db.ResultData* 结构体的差异.
+// 支持 api 响应 (*.json) 与 -record 录制的 cassette (*.jsonl), 目录递归查找
+func cmdSchema(args []string) int {
+	fset := flag.NewFlagSet("schema", flag.ContinueOnError)
+	asJson := fset.Bool("json", false, "print the report as json")
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "usage: saucenao schema [flags] <file|dir>...")
+		fmt.Fprintln(fset.Output(), "exits with", EXIT_SCHEMA_DRIFT, "if any index has unknown or missing fields")
+		fset.PrintDefaults()
+	}
+	if err := fset.Parse(args); err != nil {
+		return parseExitCode(err)
+	}
+	if fset.NArg() == 0 {
+		fset.Usage()
+		return EXIT_ERROR
+	}
+
+	var paths []string
+	for _, arg := range fset.Args() {
+		err := filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			// 直接指定的文件不限扩展名
+			if ext := filepath.Ext(path); path == arg || ext == ".json" || ext == ".jsonl" {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			errorf("%v", err)
+			return EXIT_ERROR
+		}
+	}
+
+	report := &db.SchemaReport{}
+	responses := 0
+	for _, path := range paths {
+		bodies, err := readResponseBodies(path)
+		if err != nil {
+			errorf("%s: %v", path, err)
+			return EXIT_ERROR
+		}
+		for _, body := range bodies {
+			var resp SauceNao.Response
+			if json.Unmarshal(body, &resp) != nil || resp.Results == nil {
+				continue
+			}
+			responses++
+			for _, r := range resp.Results {
+				report.Add(r.Header.IndexId, r.Data)
+			}
+		}
+	}
+
+	if *asJson {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report.Indexes)
+	} else {
+		fmt.Fprintf(os.Stderr, "%d files, %d responses, %d indexes\n", len(paths), responses, len(report.Indexes))
+		report.WriteTo(os.Stdout)
+	}
+	if report.Drifted() {
+		return EXIT_SCHEMA_DRIFT
+	}
+	return EXIT_OK
+}
+
+// readResponseBodies *.jsonl 按 cassette 读取成功的 search.php 响应, 其他文件整体作为一个响应
+func readResponseBodies(path string) ([][]byte, error) {
+	if strings.HasSuffix(path, ".jsonl") {
+		interactions, err := cassette.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		var bodies [][]byte
+		for _, in := range interactions {
+			if in.Path == SauceNao.API_PATH && in.StatusCode == http.StatusOK {
+				bodies = append(bodies, []byte(in.Body))
+			}
+		}
+		return bodies, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{data}, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+// jsonResult -jsonl 每行的格式, 搜索失败或没有结果时只有 input 与 error
+type jsonResult struct {
+	Input string `json:"input"`
+	Error string `json:"error,omitempty"`
+	*jsonMatch
+}
+
+type jsonMatch struct {
+	Similarity float64         `json:"similarity"`
+	Confidence string          `json:"confidence"`
+	IndexId    db.IndexId      `json:"index_id"`
+	IndexName  string          `json:"index_name"`
+	Thumbnail  string          `json:"thumbnail"`
+	Hidden     bool            `json:"hidden"`
+	Data       json.RawMessage `json:"data"`
+}
+
+type jsonOutput struct {
+	Input   string       `json:"input"`
+	Error   string       `json:"error,omitempty"`
+	Results []jsonResult `json:"results"`
+}
+
+func cmdSearch(args []string) int {
+	cfg, err := loadConfig(args)
+	if err != nil {
+		errorf("%v", err)
+		return EXIT_ERROR
+	}
+	fset := flag.NewFlagSet("search", flag.ContinueOnError)
+	clientFlags(fset, cfg)
+	jsonOut := fset.Bool("json", false, "print results as a JSON array")
+	jsonlOut := fset.Bool("jsonl", false, "print one JSON object per result, or one per input that failed or has no results")
+	all := fset.Bool("all", false, "include results below the minimum similarity")
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "usage: saucenao [search] [flags] <file|url|->...")
+		fset.PrintDefaults()
+	}
+	if err := fset.Parse(args); err != nil {
+		return parseExitCode(err)
+	}
+	inputs := fset.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		errorf("%v", err)
+		return EXIT_ERROR
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	code := EXIT_OK
+	var outputs []jsonOutput
+	for _, input := range inputs {
+		var image any = input
+		if input == "-" {
+			image = io.Reader(os.Stdin)
+		}
+		out := jsonOutput{Input: input}
+		resp, err := client.Search(ctx, image)
+		if err != nil {
+			errorf("%s: %v", input, err)
+			out.Error = err.Error()
+			if SauceNao.IsRateLimited(err) {
+				code = EXIT_RATE_LIMITED
+			} else if code != EXIT_RATE_LIMITED {
+				code = EXIT_ERROR
+			}
+		} else {
+			results := resp.Results
+			if !*all {
+				results = resp.FilterByConfidence(SauceNao.CONFIDENCE_LOW, nil)
+			}
+			if len(results) == 0 && code == EXIT_OK {
+				code = EXIT_NO_MATCH
+			}
+			for _, r := range results {
+				out.Results = append(out.Results, jsonResult{Input: input, jsonMatch: &jsonMatch{
+					Similarity: r.Header.SimilarityFloat(),
+					Confidence: resp.Confidence(r, nil).String(),
+					IndexId:    r.Header.IndexId,
+					IndexName:  r.Header.IndexId.String(),
+					Thumbnail:  r.Header.Thumbnail,
+					Hidden:     r.Header.IsHidden(),
+					Data:       json.RawMessage(r.DecodeData().Json("")),
+				}})
+			}
+			if !*jsonOut && !*jsonlOut {
+				printText(os.Stdout, input, resp, results)
+			}
+		}
+
+		switch {
+		case *jsonlOut:
+			enc := json.NewEncoder(os.Stdout)
+			if len(out.Results) == 0 {
+				enc.Encode(jsonResult{Input: input, Error: out.Error})
+			}
+			for _, r := range out.Results {
+				enc.Encode(r)
+			}
+		case *jsonOut:
+			outputs = append(outputs, out)
+		}
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(outputs)
+	}
+	return code
+}
+
+func printText(w io.Writer, input string, resp *SauceNao.Response, results []SauceNao.Result) {
+	fmt.Fprintf(w, "== %s\n", input)
+	if len(results) == 0 {
+		fmt.Fprintln(w, "no match")
+		return
+	}
+	for _, r := range results {
+		fmt.Fprintf(w, "[%s] %s%% (%s)\n%s\n\n",
+			r.Header.IndexId, r.Header.Similarity, resp.Confidence(r, nil), r.DecodeData())
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+	"github.com/Miuzarte/SauceNAO-go/server"
+)
+
+func cmdServe(args []string) int {
+	cfg, err := loadConfig(args)
+	if err != nil {
+		errorf("%v", err)
+		return EXIT_ERROR
+	}
+	fset := flag.NewFlagSet("serve", flag.ContinueOnError)
+	clientFlags(fset, cfg)
+	addr := fset.String("addr", "127.0.0.1:8080", "listen address")
+	cacheSize := fset.Int("cache-size", 1024, "cached responses, 0 to disable")
+	cacheTtl := fset.Duration("cache-ttl", 24*time.Hour, "cached response lifetime")
+	callersPath := fset.String("callers", "", "JSON file with an array of callers (name, token, weight, daily_share, daily_limit, batch_only); no auth if empty")
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "usage: saucenao serve [flags]")
+		fset.PrintDefaults()
+	}
+	if err := fset.Parse(args); err != nil {
+		return parseExitCode(err)
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		errorf("%v", err)
+		return EXIT_ERROR
+	}
+	client.Metrics = SauceNao.NewMetrics()
+	if *cacheSize > 0 {
+		client.Cache = SauceNao.NewMemoryCache(*cacheSize, *cacheTtl)
+	}
+
+	var callers []server.Caller
+	if *callersPath != "" {
+		data, err := os.ReadFile(*callersPath)
+		if err == nil {
+			err = json.Unmarshal(data, &callers)
+		}
+		if err != nil {
+			errorf("callers: %v", err)
+			return EXIT_ERROR
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	srv := &http.Server{Addr: *addr, Handler: &server.Server{Client: client, Callers: callers}}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	fmt.Fprintf(os.Stderr, "listening on %s\n", *addr)
+	err = srv.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errorf("%v", err)
+		return EXIT_ERROR
+	}
+	return EXIT_OK
+}
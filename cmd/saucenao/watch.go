@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+	"github.com/Miuzarte/SauceNAO-go/watch"
+)
+
+func cmdWatch(args []string) int {
+	cfg, err := loadConfig(args)
+	if err != nil {
+		errorf("%v", err)
+		return EXIT_ERROR
+	}
+	fset := flag.NewFlagSet("watch", flag.ContinueOnError)
+	clientFlags(fset, cfg)
+	statePath := fset.String("state", "", "state file (default <dir>/"+watch.STATE_FILE+")")
+	interval := fset.Duration("interval", 0, "poll interval (default 10s)")
+	topN := fset.Int("top", 3, "results kept per file")
+	once := fset.Bool("once", false, "scan once and exit")
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "usage: saucenao watch [flags] <dir>")
+		fset.PrintDefaults()
+	}
+	if err := fset.Parse(args); err != nil {
+		return parseExitCode(err)
+	}
+	if fset.NArg() != 1 {
+		fset.Usage()
+		return EXIT_ERROR
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		errorf("%v", err)
+		return EXIT_ERROR
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	w := &watch.Watcher{
+		Client:    client,
+		Dir:       fset.Arg(0),
+		Interval:  *interval,
+		StatePath: *statePath,
+		TopN:      *topN,
+		OnResult: func(ev watch.Event) {
+			switch {
+			case ev.Err != nil:
+				errorf("%s: %v", ev.Path, ev.Err)
+			case ev.Skipped:
+				fmt.Fprintf(os.Stderr, "%s: already processed\n", ev.Path)
+			default:
+				fmt.Fprintf(os.Stderr, "%s: %d results\n", ev.Path, len(ev.Sidecar.Results))
+			}
+		},
+	}
+	if *once {
+		w.Settle = -1
+		err = w.Scan(ctx)
+	} else {
+		err = w.Run(ctx)
+	}
+	switch {
+	case errors.Is(err, SauceNao.ErrLongLimit):
+		errorf("%v", err)
+		return EXIT_RATE_LIMITED
+	case err != nil && !errors.Is(err, context.Canceled):
+		errorf("%v", err)
+		return EXIT_ERROR
+	}
+	return EXIT_OK
+}
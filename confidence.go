@@ -0,0 +1,101 @@
+package SauceNao
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type Confidence int
+
+const (
+	CONFIDENCE_BELOW_MINIMUM Confidence = iota // 低于 MinimumSimilarity
+	CONFIDENCE_LOW
+	CONFIDENCE_MEDIUM
+	CONFIDENCE_HIGH
+)
+
+func (c Confidence) String() string {
+	switch c {
+	case CONFIDENCE_BELOW_MINIMUM:
+		return "below-minimum"
+	case CONFIDENCE_LOW:
+		return "low"
+	case CONFIDENCE_MEDIUM:
+		return "medium"
+	case CONFIDENCE_HIGH:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+// ConfidenceThresholds 相似度分级阈值, 均为百分数
+type ConfidenceThresholds struct {
+	High   float64 // >= High 为 [CONFIDENCE_HIGH]
+	Medium float64 // >= Medium 为 [CONFIDENCE_MEDIUM], 其余不低于最低相似度的为 [CONFIDENCE_LOW]
+}
+
+var DefaultConfidenceThresholds = ConfidenceThresholds{
+	High:   85,
+	Medium: 70,
+}
+
+// SimilarityFloat 解析 Similarity, 格式错误时返回 0
+func (h ResultHeader) SimilarityFloat() float64 {
+	f, _ := strconv.ParseFloat(strings.TrimSpace(h.Similarity), 64)
+	return f
+}
+
+// IsHidden 结果是否被 SauceNAO 判定为露骨内容而隐藏缩略图
+func (h ResultHeader) IsHidden() bool {
+	return h.Hidden != 0
+}
+
+// HasDupes 该结果在索引内是否还有重复项
+func (h ResultHeader) HasDupes() bool {
+	return h.Dupes > 0
+}
+
+// Confidence 根据相似度分级, minimumSimilarity 通常取 [ResponseHeader.MinimumSimilarity],
+// th 为 nil 时使用 [DefaultConfidenceThresholds]
+func (h ResultHeader) Confidence(minimumSimilarity float64, th *ConfidenceThresholds) Confidence {
+	if th == nil {
+		th = &DefaultConfidenceThresholds
+	}
+	sim := h.SimilarityFloat()
+	switch {
+	case sim < minimumSimilarity:
+		return CONFIDENCE_BELOW_MINIMUM
+	case sim >= th.High:
+		return CONFIDENCE_HIGH
+	case sim >= th.Medium:
+		return CONFIDENCE_MEDIUM
+	default:
+		return CONFIDENCE_LOW
+	}
+}
+
+// Confidence 以本次响应的 MinimumSimilarity 对结果分级
+func (r *Response) Confidence(result Result, th *ConfidenceThresholds) Confidence {
+	return result.Header.Confidence(r.Header.MinimumSimilarity, th)
+}
+
+// FilterByConfidence 返回分级不低于 min 的结果, 保持原顺序
+func (r *Response) FilterByConfidence(min Confidence, th *ConfidenceThresholds) []Result {
+	var ret []Result
+	for _, result := range r.Results {
+		if r.Confidence(result, th) >= min {
+			ret = append(ret, result)
+		}
+	}
+	return ret
+}
+
+// SortBySimilarity 按相似度降序原地排序 Results
+func (r *Response) SortBySimilarity() {
+	slices.SortStableFunc(r.Results, func(a, b Result) int {
+		return cmp.Compare(b.Header.SimilarityFloat(), a.Header.SimilarityFloat())
+	})
+}
@@ -0,0 +1,81 @@
+package SauceNao
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestConfidence(t *testing.T) {
+	custom := &ConfidenceThresholds{High: 90, Medium: 60}
+	for _, tt := range []struct {
+		similarity string
+		minimum    float64
+		th         *ConfidenceThresholds
+		want       Confidence
+	}{
+		{"49.99", 50, nil, CONFIDENCE_BELOW_MINIMUM},
+		{"50.00", 50, nil, CONFIDENCE_LOW},
+		{"50.01", 50, nil, CONFIDENCE_LOW},
+		{"69.99", 50, nil, CONFIDENCE_LOW},
+		{"70.00", 50, nil, CONFIDENCE_MEDIUM},
+		{"84.99", 50, nil, CONFIDENCE_MEDIUM},
+		{"85.00", 50, nil, CONFIDENCE_HIGH},
+		{"100.00", 50, nil, CONFIDENCE_HIGH},
+		// 最低相似度高于阈值时优先判为低于最低相似度
+		{"86.00", 87.5, nil, CONFIDENCE_BELOW_MINIMUM},
+		{"87.50", 87.5, nil, CONFIDENCE_HIGH},
+		{"59.99", 40, custom, CONFIDENCE_LOW},
+		{"60.00", 40, custom, CONFIDENCE_MEDIUM},
+		{"89.99", 40, custom, CONFIDENCE_MEDIUM},
+		{"90.00", 40, custom, CONFIDENCE_HIGH},
+		// 无法解析视为 0
+		{"", 0, nil, CONFIDENCE_LOW},
+		{"n/a", 1, nil, CONFIDENCE_BELOW_MINIMUM},
+		{" 85.00 ", 50, nil, CONFIDENCE_HIGH},
+	} {
+		h := ResultHeader{Similarity: tt.similarity}
+		if got := h.Confidence(tt.minimum, tt.th); got != tt.want {
+			t.Errorf("Confidence(%q, min %g, %+v) = %s, want %s", tt.similarity, tt.minimum, tt.th, got, tt.want)
+		}
+	}
+}
+
+func TestFilterAndSortBySimilarity(t *testing.T) {
+	resp := &Response{
+		Header: ResponseHeader{MinimumSimilarity: 55.5},
+		Results: []Result{
+			{Header: ResultHeader{Similarity: "55.49", IndexName: "a"}},
+			{Header: ResultHeader{Similarity: "85.00", IndexName: "b"}},
+			{Header: ResultHeader{Similarity: "55.50", IndexName: "c"}},
+			{Header: ResultHeader{Similarity: "70.00", IndexName: "d"}},
+			{Header: ResultHeader{Similarity: "85.00", IndexName: "e"}},
+		},
+	}
+	names := func(results []Result) []string {
+		var ret []string
+		for _, r := range results {
+			ret = append(ret, r.Header.IndexName)
+		}
+		return ret
+	}
+
+	for _, tt := range []struct {
+		min  Confidence
+		want []string
+	}{
+		{CONFIDENCE_BELOW_MINIMUM, []string{"a", "b", "c", "d", "e"}},
+		{CONFIDENCE_LOW, []string{"b", "c", "d", "e"}},
+		{CONFIDENCE_MEDIUM, []string{"b", "d", "e"}},
+		{CONFIDENCE_HIGH, []string{"b", "e"}},
+	} {
+		if got := names(resp.FilterByConfidence(tt.min, nil)); !slices.Equal(got, tt.want) {
+			t.Errorf("FilterByConfidence(%s) = %v, want %v", tt.min, got, tt.want)
+		}
+	}
+
+	// 相同相似度保持原顺序
+	resp.SortBySimilarity()
+	if got, want := names(resp.Results), []string{"b", "e", "d", "c", "a"}; !slices.Equal(got, want) {
+		t.Errorf("SortBySimilarity() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// String() 中用到的格式化函数, 在 indexes.json 的 string 模板中调用
+
+// formatTime 将 RFC3339 时间转为本地时间, 无法解析时原样返回
+func formatTime(s string) string {
+	const layout = "2006/01/02 15:04:05"
+	if s == "" {
+		return s
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.In(time.Local).Format(layout)
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.In(time.Local).Format(layout)
+	}
+	return s
+}
+
+// optional v 为零值时返回 "", 否则按 format 格式化
+func optional[T comparable](format string, v T) string {
+	var zero T
+	if v == zero {
+		return ""
+	}
+	return fmt.Sprintf(format, v)
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Fields 返回索引对应结构体的 json 字段名 (已排序).
+// 未知索引, 或结构体尚未完成 (只有 Todo 字段) 时 ok 为 false
+func (di IndexId) Fields() (fields []string, ok bool) {
+	if di < 0 || int(di) >= len(dbIdToType) || dbIdToType[di] == nil {
+		return nil, false
+	}
+	t := dbIdToType[di].Elem()
+	if _, todo := t.FieldByName("Todo"); todo {
+		return nil, false
+	}
+	return jsonFields(t), true
+}
+
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		fields = append(fields, name)
+	}
+	slices.Sort(fields)
+	return fields
+}
+
+// Drift 一条结果的 data 与对应结构体字段的差异, 用于发现 SauceNAO 新增或移除的字段
+type Drift struct {
+	IndexId IndexId
+	Unknown []string // data 中有但结构体没有, 解码时被丢弃
+	Missing []string // 结构体中有但 data 没有
+	Untyped bool     // 索引没有完成的结构体, data 的所有字段都算作 Unknown
+}
+
+// CheckSchema 比较 data 的键与索引对应结构体的 json 字段
+func CheckSchema(di IndexId, data map[string]any) Drift {
+	d := Drift{IndexId: di}
+	fields, ok := di.Fields()
+	d.Untyped = !ok
+	for _, k := range slices.Sorted(maps.Keys(data)) {
+		if _, found := slices.BinarySearch(fields, k); !found {
+			d.Unknown = append(d.Unknown, k)
+		}
+	}
+	for _, f := range fields {
+		if _, found := data[f]; !found {
+			d.Missing = append(d.Missing, f)
+		}
+	}
+	return d
+}
+
+// Empty 没有差异
+func (d Drift) Empty() bool {
+	return len(d.Unknown) == 0 && len(d.Missing) == 0
+}
+
+func (d Drift) String() string {
+	return fmt.Sprintf("%d %s: unknown %v, missing %v", d.IndexId, d.IndexId, d.Unknown, d.Missing)
+}
+
+// SchemaReport 汇总多条结果的 [Drift], 零值可用
+type SchemaReport struct {
+	Indexes map[IndexId]*IndexSchema
+}
+
+// IndexSchema 单个索引的汇总, 计数为出现该情况的结果数
+type IndexSchema struct {
+	Results int
+	Untyped bool
+	Unknown map[string]int
+	Missing map[string]int
+}
+
+// Add 记录一条结果
+func (sr *SchemaReport) Add(di IndexId, data map[string]any) Drift {
+	d := CheckSchema(di, data)
+	if sr.Indexes == nil {
+		sr.Indexes = map[IndexId]*IndexSchema{}
+	}
+	is := sr.Indexes[di]
+	if is == nil {
+		is = &IndexSchema{Untyped: d.Untyped, Unknown: map[string]int{}, Missing: map[string]int{}}
+		sr.Indexes[di] = is
+	}
+	is.Results++
+	for _, k := range d.Unknown {
+		is.Unknown[k]++
+	}
+	for _, k := range d.Missing {
+		is.Missing[k]++
+	}
+	return d
+}
+
+// Drifted 是否有任一索引存在差异
+func (sr *SchemaReport) Drifted() bool {
+	for _, is := range sr.Indexes {
+		if len(is.Unknown) > 0 || len(is.Missing) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteTo 按索引 id 输出有差异的字段与出现次数
+//
+//	41 Twitter (12 results)
+//	  unknown: like_count 12/12
+//	  missing: created_at 3/12
+func (sr *SchemaReport) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	for _, di := range slices.Sorted(maps.Keys(sr.Indexes)) {
+		is := sr.Indexes[di]
+		if len(is.Unknown) == 0 && len(is.Missing) == 0 {
+			continue
+		}
+		untyped := ""
+		if is.Untyped {
+			untyped = ", untyped"
+		}
+		fmt.Fprintf(&b, "%d %s (%d results%s)\n", di, di, is.Results, untyped)
+		writeFieldCounts(&b, "unknown", is.Unknown, is.Results)
+		writeFieldCounts(&b, "missing", is.Missing, is.Results)
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func writeFieldCounts(b *strings.Builder, label string, counts map[string]int, total int) {
+	if len(counts) == 0 {
+		return
+	}
+	parts := make([]string, 0, len(counts))
+	for _, k := range slices.Sorted(maps.Keys(counts)) {
+		parts = append(parts, fmt.Sprintf("%s %d/%d", k, counts[k], total))
+	}
+	fmt.Fprintf(b, "  %s: %s\n", label, strings.Join(parts, ", "))
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCheckSchema(t *testing.T) {
+	fields, ok := TWITTER.Fields()
+	if !ok || !slices.Contains(fields, "tweet_id") {
+		t.Fatalf("twitter fields = %v", fields)
+	}
+	if _, ok := NIJIE.Fields(); ok {
+		t.Error("todo struct should not report fields")
+	}
+
+	data := map[string]any{}
+	for _, f := range fields {
+		data[f] = ""
+	}
+	if d := CheckSchema(TWITTER, data); !d.Empty() {
+		t.Errorf("complete data: %v", d)
+	}
+
+	delete(data, "created_at")
+	data["like_count"] = 1
+	d := CheckSchema(TWITTER, data)
+	if !slices.Equal(d.Unknown, []string{"like_count"}) || !slices.Equal(d.Missing, []string{"created_at"}) {
+		t.Errorf("drift = %v", d)
+	}
+
+	d = CheckSchema(NIJIE, map[string]any{"nijie_id": 1})
+	if !d.Untyped || !slices.Equal(d.Unknown, []string{"nijie_id"}) {
+		t.Errorf("untyped = %v", d)
+	}
+	if d = CheckSchema(IndexId(63), nil); !d.Untyped || !d.Empty() {
+		t.Errorf("unknown index = %v", d)
+	}
+}
+
+func TestSchemaReport(t *testing.T) {
+	var sr SchemaReport
+	full := map[string]any{}
+	fields, _ := TWITTER.Fields()
+	for _, f := range fields {
+		full[f] = ""
+	}
+	sr.Add(TWITTER, full)
+	sr.Add(PIXIV, map[string]any{"pixiv_id": 1, "title": "", "member_name": "", "member_id": 1, "ext_urls": nil})
+	if sr.Drifted() {
+		t.Fatal("no drift expected")
+	}
+	full["like_count"] = 1
+	sr.Add(TWITTER, full)
+
+	var b strings.Builder
+	sr.WriteTo(&b)
+	want := "41 Twitter (2 results)\n  unknown: like_count 1/2\n"
+	if !sr.Drifted() || b.String() != want {
+		t.Errorf("report = %q", b.String())
+	}
+}
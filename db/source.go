@@ -0,0 +1,244 @@
+package db
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// SourceInfo 为 [ClassifySource] 的识别结果
+type SourceInfo struct {
+	Site       Site
+	Raw        string   // 原始字符串
+	Url        *url.URL // 规范化后的链接, 图床链接会改写为作品页, 无法解析时为 nil
+	PostId     string   // 作品 id
+	UserId     string   // 用户数字 id
+	UserHandle string   // 用户名 / 个性域名
+}
+
+// IsPost 是否识别到了具体作品
+func (si SourceInfo) IsPost() bool {
+	return si.Site != SITE_UNKNOWN && si.PostId != ""
+}
+
+var (
+	// i.pximg.net/img-original/img/2020/01/01/00/00/00/12345678_p0.png
+	// i.pximg.net/c/600x1200_90/img-master/img/.../12345678_p0_master1200.jpg
+	rePximg = regexp.MustCompile(`/img/(?:\d+/){6}(\d+)_`)
+	// pbs.twimg.com 无法反查推文, 仅识别 twitter.com / x.com 等
+	reTweet       = regexp.MustCompile(`^/([^/]+)/status(?:es)?/(\d+)`)
+	reTweetWeb    = regexp.MustCompile(`^/i/web/status/(\d+)`)
+	reLofterPost  = regexp.MustCompile(`^/post/([0-9a-f]+_[0-9a-f]+)`)
+	reFanboxPost  = regexp.MustCompile(`^/posts/(\d+)`)
+	reFanboxAt    = regexp.MustCompile(`^/@([^/]+)(?:/posts/(\d+))?`)
+	reFanboxOld   = regexp.MustCompile(`^/fanbox/creator/(\d+)(?:/post/(\d+))?`)
+	reSkeb        = regexp.MustCompile(`^/@([^/]+)(?:/works/(\d+))?`)
+	rePawoo       = regexp.MustCompile(`^/@([^/]+)(?:/(\d+))?(?:/|$)`) // /@{user}/{status id}
+	reWeiboDetail = regexp.MustCompile(`^/(?:detail|status)/(\w+)`)
+	reWeiboPost   = regexp.MustCompile(`^/(\d+)/(\w+)`)
+	rePixivWork   = regexp.MustCompile(`^/(?:[a-z]{2}/)?(?:artworks|i)/(\d+)`)
+	rePixivUser   = regexp.MustCompile(`^/(?:[a-z]{2}/)?(?:users|u)/(\d+)`)
+	reNumPath     = regexp.MustCompile(`/(\d+)(?:/|$)`)
+)
+
+// twitterReserved twitter.com 下不是用户名的一级路径
+var twitterReserved = []string{
+	"i", "intent", "home", "search", "hashtag", "share", "settings",
+	"explore", "notifications", "messages", "login", "signup", "compose",
+}
+
+// ClassifySource 识别 booru 等站点 source 字段中的链接,
+// 提取平台、作品 id、用户 id/用户名, 并将已知图床链接改写为作品页
+func ClassifySource(raw string) SourceInfo {
+	si := SourceInfo{Raw: raw}
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return si
+	}
+	if !strings.Contains(s, "://") {
+		if !strings.Contains(s, ".") || !strings.Contains(s, "/") || strings.ContainsAny(s, " \t") {
+			// 不是链接, 比如作品名
+			return si
+		}
+		s = "https://" + strings.TrimPrefix(s, "//")
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return si
+	}
+	if u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+	si.Url = u
+	si.Site = SiteOf(u)
+
+	host := strings.ToLower(u.Hostname())
+	path := u.EscapedPath()
+	query := u.Query()
+
+	switch si.Site {
+	case SITE_PIXIV:
+		switch {
+		case strings.HasSuffix(host, "pximg.net"):
+			if m := rePximg.FindStringSubmatch(path); m != nil {
+				si.PostId = m[1]
+			}
+		case strings.HasSuffix(path, "member_illust.php"):
+			si.PostId = query.Get("illust_id")
+			si.UserId = query.Get("id")
+		case strings.HasSuffix(path, "member.php"):
+			si.UserId = query.Get("id")
+		default:
+			if m := rePixivWork.FindStringSubmatch(path); m != nil {
+				si.PostId = m[1]
+			} else if m := rePixivUser.FindStringSubmatch(path); m != nil {
+				si.UserId = m[1]
+			} else if m := reFanboxOld.FindStringSubmatch(path); m != nil {
+				si.Site = SITE_FANBOX
+				si.UserId, si.PostId = m[1], m[2]
+			}
+		}
+		switch {
+		case si.Site == SITE_FANBOX:
+		case si.PostId != "":
+			si.Url = rewriteUrl(si.Url, "https://www.pixiv.net/artworks/"+si.PostId)
+		case si.UserId != "":
+			si.Url = rewriteUrl(si.Url, "https://www.pixiv.net/users/"+si.UserId)
+		}
+
+	case SITE_TWITTER:
+		if m := reTweetWeb.FindStringSubmatch(path); m != nil {
+			si.PostId = m[1]
+			si.Url = rewriteUrl(si.Url, "https://x.com/i/web/status/"+si.PostId)
+		} else if m := reTweet.FindStringSubmatch(path); m != nil {
+			si.UserHandle, si.PostId = m[1], m[2]
+			si.Url = rewriteUrl(si.Url, "https://x.com/"+si.UserHandle+"/status/"+si.PostId)
+		} else if seg := firstSegment(path); seg != "" && !strings.Contains(seg, ".") && !slices.Contains(twitterReserved, strings.ToLower(seg)) {
+			si.UserHandle = seg
+			si.Url = rewriteUrl(si.Url, "https://x.com/"+si.UserHandle)
+		}
+
+	case SITE_LOFTER:
+		if sub, _, ok := strings.Cut(host, ".lofter.com"); ok && sub != "www" {
+			si.UserHandle = sub
+		}
+		if m := reLofterPost.FindStringSubmatch(path); m != nil {
+			si.PostId = m[1]
+		}
+
+	case SITE_FANBOX:
+		if sub, _, ok := strings.Cut(host, ".fanbox.cc"); ok && sub != "www" && sub != "downloads" {
+			si.UserHandle = sub
+			if m := reFanboxPost.FindStringSubmatch(path); m != nil {
+				si.PostId = m[1]
+			}
+		} else if m := reFanboxAt.FindStringSubmatch(path); m != nil {
+			si.UserHandle, si.PostId = m[1], m[2]
+		}
+		switch {
+		case si.UserHandle != "" && si.PostId != "":
+			si.Url = rewriteUrl(si.Url, "https://"+si.UserHandle+".fanbox.cc/posts/"+si.PostId)
+		case si.UserHandle != "":
+			si.Url = rewriteUrl(si.Url, "https://"+si.UserHandle+".fanbox.cc")
+		}
+
+	case SITE_SKEB:
+		if m := reSkeb.FindStringSubmatch(path); m != nil {
+			si.UserHandle, si.PostId = m[1], m[2]
+		}
+
+	case SITE_WEIBO:
+		if m := reWeiboDetail.FindStringSubmatch(path); m != nil {
+			si.PostId = m[1]
+		} else if m := reWeiboPost.FindStringSubmatch(path); m != nil {
+			si.UserId, si.PostId = m[1], m[2]
+		} else if id := query.Get("id"); id != "" {
+			si.PostId = id
+		}
+
+	case SITE_DANBOORU:
+		// 旧版链接为 /post/show/{id}
+		if strings.HasPrefix(path, "/posts/") || strings.HasPrefix(path, "/post/show/") {
+			if m := reNumPath.FindStringSubmatch(path); m != nil {
+				si.PostId = m[1]
+			}
+		}
+
+	case SITE_GELBOORU:
+		if query.Get("s") == "view" {
+			si.PostId = query.Get("id")
+		}
+
+	case SITE_YANDERE, SITE_KONACHAN, SITE_IDOLCOMPLEX, SITE_E621, SITE_SANKAKU:
+		if strings.Contains(path, "/post") {
+			if m := reNumPath.FindStringSubmatch(path); m != nil {
+				si.PostId = m[1]
+			}
+		}
+
+	case SITE_NIJIE:
+		si.PostId = query.Get("id")
+
+	case SITE_SEIGA:
+		if _, id, ok := strings.Cut(path, "/seiga/im"); ok {
+			si.PostId = id
+		}
+
+	case SITE_DEVIANTART:
+		if _, rest, ok := strings.Cut(path, "/art/"); ok {
+			// /{user}/art/{slug}-{id}
+			if i := strings.LastIndex(rest, "-"); i >= 0 {
+				si.PostId = rest[i+1:]
+			} else {
+				si.PostId = rest
+			}
+			si.UserHandle = firstSegment(path)
+		} else if _, id, ok := strings.Cut(path, "/view/"); ok {
+			si.PostId = id
+		}
+
+	case SITE_ARTSTATION:
+		if _, id, ok := strings.Cut(path, "/artwork/"); ok {
+			si.PostId = id
+		}
+
+	case SITE_FURAFFINITY:
+		if strings.HasPrefix(path, "/view/") || strings.HasPrefix(path, "/full/") {
+			if m := reNumPath.FindStringSubmatch(path); m != nil {
+				si.PostId = m[1]
+			}
+		}
+
+	case SITE_PAWOO:
+		if m := rePawoo.FindStringSubmatch(path); m != nil {
+			si.UserHandle, si.PostId = m[1], m[2]
+		}
+	}
+
+	si.PostId = strings.TrimSuffix(si.PostId, "/")
+	return si
+}
+
+func firstSegment(path string) string {
+	seg, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return seg
+}
+
+// rewriteUrl 解析改写后的链接, 失败时保留原链接
+func rewriteUrl(orig *url.URL, s string) *url.URL {
+	u, err := url.Parse(s)
+	if err != nil {
+		return orig
+	}
+	return u
+}
+
+// addSource 将 source 字段识别后加入链接列表
+func (s *siteUrls) addSource(source string) {
+	si := ClassifySource(source)
+	if si.Url == nil {
+		return
+	}
+	s.addRaw(si.Site, si.Url.String())
+}
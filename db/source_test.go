@@ -0,0 +1,77 @@
+package db
+
+import "testing"
+
+func TestClassifySource(t *testing.T) {
+	tests := []struct {
+		raw        string
+		site       Site
+		postId     string
+		userId     string
+		userHandle string
+		url        string
+	}{
+		{"https://i.pximg.net/img-original/img/2020/01/02/03/04/05/78901234_p0.png", SITE_PIXIV, "78901234", "", "", "https://www.pixiv.net/artworks/78901234"},
+		{"https://i.pximg.net/c/600x1200_90/img-master/img/2020/01/02/03/04/05/78901234_p1_master1200.jpg", SITE_PIXIV, "78901234", "", "", "https://www.pixiv.net/artworks/78901234"},
+		{"https://www.pixiv.net/member_illust.php?mode=medium&illust_id=123", SITE_PIXIV, "123", "", "", "https://www.pixiv.net/artworks/123"},
+		{"https://www.pixiv.net/en/artworks/456", SITE_PIXIV, "456", "", "", "https://www.pixiv.net/artworks/456"},
+		{"http://www.pixiv.net/users/789", SITE_PIXIV, "", "789", "", "https://www.pixiv.net/users/789"},
+		{"https://twitter.com/some_artist/status/1234567890123456789", SITE_TWITTER, "1234567890123456789", "", "some_artist", "https://x.com/some_artist/status/1234567890123456789"},
+		{"https://x.com/i/web/status/42", SITE_TWITTER, "42", "", "", "https://x.com/i/web/status/42"},
+		{"https://mobile.twitter.com/some_artist", SITE_TWITTER, "", "", "some_artist", "https://x.com/some_artist"},
+		{"https://twitter.com/intent/tweet?text=a", SITE_TWITTER, "", "", "", "https://twitter.com/intent/tweet?text=a"},
+		{"https://x.com/home", SITE_TWITTER, "", "", "", "https://x.com/home"},
+		{"https://x.com/search?q=a", SITE_TWITTER, "", "", "", "https://x.com/search?q=a"},
+		{"https://twitter.com/hashtag/art", SITE_TWITTER, "", "", "", "https://twitter.com/hashtag/art"},
+		{"https://twitter.com/share", SITE_TWITTER, "", "", "", "https://twitter.com/share"},
+		{"https://x.com/settings/profile", SITE_TWITTER, "", "", "", "https://x.com/settings/profile"},
+		{"https://x.com/i/lists/1", SITE_TWITTER, "", "", "", "https://x.com/i/lists/1"},
+		{"https://someone.lofter.com/post/1d2e3f_2b4c6d8", SITE_LOFTER, "1d2e3f_2b4c6d8", "", "someone", "https://someone.lofter.com/post/1d2e3f_2b4c6d8"},
+		{"https://creator.fanbox.cc/posts/1234", SITE_FANBOX, "1234", "", "creator", "https://creator.fanbox.cc/posts/1234"},
+		{"https://www.fanbox.cc/@creator/posts/1234", SITE_FANBOX, "1234", "", "creator", "https://creator.fanbox.cc/posts/1234"},
+		{"https://skeb.jp/@neko_satsuma/works/21", SITE_SKEB, "21", "", "neko_satsuma", "https://skeb.jp/@neko_satsuma/works/21"},
+		{"https://weibo.com/1234567890/AbCdEfGhI", SITE_WEIBO, "AbCdEfGhI", "1234567890", "", "https://weibo.com/1234567890/AbCdEfGhI"},
+		{"https://m.weibo.cn/detail/4567890123456789", SITE_WEIBO, "4567890123456789", "", "", "https://m.weibo.cn/detail/4567890123456789"},
+		{"https://danbooru.donmai.us/posts/1000", SITE_DANBOORU, "1000", "", "", "https://danbooru.donmai.us/posts/1000"},
+		{"https://danbooru.donmai.us/post/show/1000", SITE_DANBOORU, "1000", "", "", "https://danbooru.donmai.us/post/show/1000"},
+		{"https://pawoo.net/@someone/100200300", SITE_PAWOO, "100200300", "", "someone", "https://pawoo.net/@someone/100200300"},
+		{"https://pawoo.net/@someone", SITE_PAWOO, "", "", "someone", "https://pawoo.net/@someone"},
+		{"https://pawoo.net/@someone/media", SITE_PAWOO, "", "", "someone", "https://pawoo.net/@someone/media"},
+		{"blue archive", SITE_UNKNOWN, "", "", "", ""},
+		{"", SITE_UNKNOWN, "", "", "", ""},
+	}
+	for _, tt := range tests {
+		si := ClassifySource(tt.raw)
+		url := ""
+		if si.Url != nil {
+			url = si.Url.String()
+		}
+		if si.Site != tt.site || si.PostId != tt.postId || si.UserId != tt.userId ||
+			si.UserHandle != tt.userHandle || url != tt.url {
+			t.Errorf("ClassifySource(%q) = {%q %q %q %q %q}, want {%q %q %q %q %q}",
+				tt.raw, si.Site, si.PostId, si.UserId, si.UserHandle, url,
+				tt.site, tt.postId, tt.userId, tt.userHandle, tt.url)
+		}
+	}
+}
+
+func TestCanonicalURLsSource(t *testing.T) {
+	rd := ResultDataDanbooru{
+		ExtUrls:    []string{"https://danbooru.donmai.us/posts/1000"},
+		DanbooruId: 1000,
+		Source:     "https://i.pximg.net/img-original/img/2020/01/02/03/04/05/78901234_p0.png",
+	}
+	want := []string{
+		"https://danbooru.donmai.us/posts/1000",
+		"https://www.pixiv.net/artworks/78901234",
+	}
+	got := rd.CanonicalURLs()
+	if len(got) != len(want) {
+		t.Fatalf("CanonicalURLs() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Errorf("CanonicalURLs()[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,821 @@
+// Code generated by gendb from indexes.json; DO NOT EDIT.
+
+package db
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+)
+
+// 0 H-Magazines
+type ResultDataHMagazines struct {
+	Title string `json:"title"`
+	Part  string `json:"part"`
+	Date  string `json:"date"`
+}
+
+func (rd ResultDataHMagazines) String() string {
+	return fmt.Sprintf(
+		`%s
+Part: %s
+Date: %s`,
+		rd.Title,
+		rd.Part,
+		rd.Date,
+	)
+}
+func (rd ResultDataHMagazines) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataHMagazines) CanonicalURLs() []SiteUrl  { return nil }
+
+// 2 H-Game CG
+type ResultDataHGameCg struct {
+	Title    string `json:"title"`
+	Company  string `json:"company"`
+	GetchuId string `json:"getchu_id"`
+}
+
+func (rd ResultDataHGameCg) String() string {
+	return fmt.Sprintf(
+		`%s
+Company: %s
+GetchuId: %s`,
+		rd.Title,
+		rd.Company,
+		rd.GetchuId,
+	)
+}
+func (rd ResultDataHGameCg) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataHGameCg) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	s.add(SITE_GETCHU, "https://www.getchu.com/soft.phtml?id=%s", rd.GetchuId)
+	return s.list()
+}
+
+// 3 DoujinshiDB
+type ResultDataDoujinshiDb struct {
+	Todo struct{}
+}
+
+func (rd ResultDataDoujinshiDb) String() string            { return "[TODO]" }
+func (rd ResultDataDoujinshiDb) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataDoujinshiDb) CanonicalURLs() []SiteUrl  { return nil }
+
+// 5 pixiv Images
+type ResultDataPixiv struct {
+	ExtUrls    []string `json:"ext_urls"`
+	Title      string   `json:"title"`
+	PixivId    int      `json:"pixiv_id"`
+	MemberName string   `json:"member_name"`
+	MemberId   int      `json:"member_id"`
+}
+
+func (rd ResultDataPixiv) String() string {
+	return fmt.Sprintf(
+		`%s
+https://www.pixiv.net/artworks/%d
+%s: https://www.pixiv.net/users/%d`,
+		rd.Title,
+		rd.PixivId,
+		rd.MemberName,
+		rd.MemberId,
+	)
+}
+func (rd ResultDataPixiv) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataPixiv) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	s.add(SITE_PIXIV, "https://www.pixiv.net/artworks/%d", rd.PixivId)
+	s.add(SITE_PIXIV, "https://www.pixiv.net/users/%d", rd.MemberId)
+	s.addExt(rd.ExtUrls)
+	return s.list()
+}
+
+// 8 Nico Nico Seiga
+type ResultDataSeiga struct {
+	ExtUrls    []string `json:"ext_urls"`
+	Title      string   `json:"title"`
+	SeigaId    int      `json:"seiga_id"`
+	MemberName string   `json:"member_name"`
+	MemberId   int      `json:"member_id"`
+}
+
+func (rd ResultDataSeiga) String() string {
+	return fmt.Sprintf(
+		`%s
+https://seiga.nicovideo.jp/seiga/im%d
+MemberName: %s
+MemberId: %d`,
+		rd.Title,
+		rd.SeigaId,
+		rd.MemberName,
+		rd.MemberId,
+	)
+}
+func (rd ResultDataSeiga) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataSeiga) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	s.add(SITE_SEIGA, "https://seiga.nicovideo.jp/seiga/im%d", rd.SeigaId)
+	s.add(SITE_SEIGA, "https://seiga.nicovideo.jp/user/illust/%d", rd.MemberId)
+	s.addExt(rd.ExtUrls)
+	return s.list()
+}
+
+// 9 Danbooru
+type ResultDataDanbooru struct {
+	ExtUrls    []string `json:"ext_urls"`
+	DanbooruId int      `json:"danbooru_id"` // "https://danbooru.donmai.us/posts/{.DanbooruId}"
+	GelbooruId int      `json:"gelbooru_id"` // "https://gelbooru.com/index.php?page=post&s=view&id={.GelbooruId}"
+	Creator    string   `json:"creator"`     // 作者 // "earosoligt"
+	Material   string   `json:"material"`    // 作品 // "blue archive"
+	Characters string   `json:"characters"`  // 角色 // "miyako (blue archive)"
+	Source     string   `json:"source"`      // url // twitter | pixiv | lofter
+}
+
+func (rd ResultDataDanbooru) String() string {
+	return fmt.Sprintf(
+		`%s
+%s
+%s
+https://danbooru.donmai.us/posts/%d%s
+%s`,
+		rd.Characters,
+		rd.Material,
+		rd.Creator,
+		rd.DanbooruId,
+		optional("\nhttps://gelbooru.com/index.php?page=post&s=view&id=%d", rd.GelbooruId),
+		rd.Source,
+	)
+}
+func (rd ResultDataDanbooru) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataDanbooru) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	s.add(SITE_DANBOORU, "https://danbooru.donmai.us/posts/%d", rd.DanbooruId)
+	s.add(SITE_GELBOORU, "https://gelbooru.com/index.php?page=post&s=view&id=%d", rd.GelbooruId)
+	s.addSource(rd.Source)
+	s.addExt(rd.ExtUrls)
+	return s.list()
+}
+
+// 10 drawr Images
+type ResultDataDrawr struct {
+	ExtUrls    []string `json:"ext_urls"`
+	Title      string   `json:"title"`
+	DrawrId    int      `json:"drawr_id"`
+	MemberName string   `json:"member_name"`
+	MemberId   int      `json:"member_id"`
+}
+
+func (rd ResultDataDrawr) String() string {
+	return fmt.Sprintf(
+		`%s
+https://drawr.net/show.php?id=%d
+MemberName: %s
+MemberId: %d`,
+		rd.Title,
+		rd.DrawrId,
+		rd.MemberName,
+		rd.MemberId,
+	)
+}
+func (rd ResultDataDrawr) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataDrawr) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	s.add(SITE_DRAWR, "https://drawr.net/show.php?id=%d", rd.DrawrId)
+	s.addExt(rd.ExtUrls)
+	return s.list()
+}
+
+// 11 Nijie Images
+type ResultDataNijie struct {
+	Todo struct{}
+}
+
+func (rd ResultDataNijie) String() string            { return "[TODO]" }
+func (rd ResultDataNijie) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataNijie) CanonicalURLs() []SiteUrl  { return nil }
+
+// 12 Yande.re
+type ResultDataYandere struct {
+	ExtUrls    []string `json:"ext_urls"`
+	YandereId  int      `json:"yandere_id"` // "https://yande.re/post/show/{.YandereId}"
+	Creator    string   `json:"creator"`    // 作者 // "momoko (momopoco)"
+	Material   string   `json:"material"`   // 作品 // "tokidoki bosotto roshia-go de dereru tonari no arya-san"
+	Characters string   `json:"characters"` // 角色 // "alisa nikolaevna kujou"
+	Source     string   `json:"source"`     // url // twitter | pixiv | lofter
+}
+
+func (rd ResultDataYandere) String() string {
+	return fmt.Sprintf(
+		`%s
+%s
+%s
+https://yande.re/post/show/%d
+%s`,
+		rd.Characters,
+		rd.Material,
+		rd.Creator,
+		rd.YandereId,
+		rd.Source,
+	)
+}
+func (rd ResultDataYandere) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataYandere) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	s.add(SITE_YANDERE, "https://yande.re/post/show/%d", rd.YandereId)
+	s.addSource(rd.Source)
+	s.addExt(rd.ExtUrls)
+	return s.list()
+}
+
+// 15 Shutterstock
+type ResultDataShutterstock struct {
+	Todo struct{}
+}
+
+func (rd ResultDataShutterstock) String() string            { return "[TODO]" }
+func (rd ResultDataShutterstock) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataShutterstock) CanonicalURLs() []SiteUrl  { return nil }
+
+// 16 FAKKU
+type ResultDataFakku struct {
+	ExtUrls []string `json:"ext_urls"`
+	Source  string   `json:"source"`
+	Creator string   `json:"creator"`
+}
+
+func (rd ResultDataFakku) String() string {
+	return fmt.Sprintf(
+		`Source: %s
+Creator: %s
+%s`,
+		rd.Source,
+		rd.Creator,
+		strings.Join(rd.ExtUrls, "\n"),
+	)
+}
+func (rd ResultDataFakku) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataFakku) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	s.addExt(rd.ExtUrls)
+	return s.list()
+}
+
+// 18|38
+type ResultDataDoujin struct {
+	Source  string   `json:"source"`
+	Creator []string `json:"creator"`
+	EngName string   `json:"eng_name"`
+	JpName  string   `json:"jp_name"`
+}
+
+func (rd ResultDataDoujin) String() string {
+	return fmt.Sprintf(
+		`%s
+%s
+Creator: %s`,
+		rd.Source,
+		cmp.Or(rd.JpName, rd.EngName),
+		strings.Join(rd.Creator, ", "),
+	)
+}
+func (rd ResultDataDoujin) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataDoujin) CanonicalURLs() []SiteUrl  { return nil }
+
+// 18 H-Misc (nH)
+type ResultDataNHentai = ResultDataDoujin
+
+// 19 2D-Market
+type ResultDataMarket2d struct {
+	Todo struct{}
+}
+
+func (rd ResultDataMarket2d) String() string            { return "[TODO]" }
+func (rd ResultDataMarket2d) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataMarket2d) CanonicalURLs() []SiteUrl  { return nil }
+
+// 20 MediBang
+type ResultDataMediBang struct {
+	Todo struct{}
+}
+
+func (rd ResultDataMediBang) String() string            { return "[TODO]" }
+func (rd ResultDataMediBang) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataMediBang) CanonicalURLs() []SiteUrl  { return nil }
+
+// 21 Anime
+type ResultDataAnime struct {
+	ExtUrls   []string `json:"ext_urls"`
+	Source    string   `json:"source"`     // 作品
+	AnidbAid  int      `json:"anidb_aid"`  // "https://anidb.net/anime/{.AnidbAid}"
+	AnilistId int      `json:"anilist_id"` // "https://anilist.co/anime/{.AnilistId}"
+	MalId     int      `json:"mal_id"`     // "https://myanimelist.net/anime/{.MalId}"
+	Part      string   `json:"part"`
+	Year      string   `json:"year"`
+	EstTime   string   `json:"est_time"`
+}
+
+func (rd ResultDataAnime) String() string {
+	return fmt.Sprintf(
+		`%s
+https://anidb.net/anime/%d
+https://anilist.co/anime/%d
+https://myanimelist.net/anime/%d
+Part: %s  Year: %s  Est: %s`,
+		rd.Source,
+		rd.AnidbAid,
+		rd.AnilistId,
+		rd.MalId,
+		rd.Part,
+		rd.Year,
+		rd.EstTime,
+	)
+}
+func (rd ResultDataAnime) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataAnime) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	s.add(SITE_ANIDB, "https://anidb.net/anime/%d", rd.AnidbAid)
+	s.add(SITE_ANILIST, "https://anilist.co/anime/%d", rd.AnilistId)
+	s.add(SITE_MYANIMELIST, "https://myanimelist.net/anime/%d", rd.MalId)
+	s.addExt(rd.ExtUrls)
+	return s.list()
+}
+
+// 22 H-Anime
+type ResultDataHAnime struct {
+	Todo struct{}
+}
+
+func (rd ResultDataHAnime) String() string            { return "[TODO]" }
+func (rd ResultDataHAnime) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataHAnime) CanonicalURLs() []SiteUrl  { return nil }
+
+// 23 Movies
+type ResultDataMovies struct {
+	ExtUrls []string `json:"ext_urls"`
+	Source  string   `json:"source"`
+	ImdbId  string   `json:"imdb_id"` // "https://www.imdb.com/title/{.ImdbId}"
+	Part    string   `json:"part"`
+	Year    string   `json:"year"`
+	EstTime string   `json:"est_time"`
+}
+
+func (rd ResultDataMovies) String() string {
+	return fmt.Sprintf(
+		`%s
+https://www.imdb.com/title/%s
+Part: %s  Year: %s  Est: %s`,
+		rd.Source,
+		rd.ImdbId,
+		rd.Part,
+		rd.Year,
+		rd.EstTime,
+	)
+}
+func (rd ResultDataMovies) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataMovies) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	s.add(SITE_IMDB, "https://www.imdb.com/title/%s", rd.ImdbId)
+	s.addExt(rd.ExtUrls)
+	return s.list()
+}
+
+// 24 Shows
+type ResultDataShows struct {
+	Todo struct{}
+}
+
+func (rd ResultDataShows) String() string            { return "[TODO]" }
+func (rd ResultDataShows) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataShows) CanonicalURLs() []SiteUrl  { return nil }
+
+// 25 Gelbooru
+type ResultDataGelbooru struct {
+	ExtUrls    []string `json:"ext_urls"`
+	GelbooruId int      `json:"gelbooru_id"`
+	Creator    string   `json:"creator"`    // ""
+	Material   string   `json:"material"`   // ""
+	Characters string   `json:"characters"` // ""
+	Source     string   `json:"source"`
+}
+
+func (rd ResultDataGelbooru) String() string {
+	return fmt.Sprintf(
+		`%s
+%s
+%s
+https://gelbooru.com/index.php?page=post&s=view&id=%d
+%s`,
+		rd.Characters,
+		rd.Material,
+		rd.Creator,
+		rd.GelbooruId,
+		rd.Source,
+	)
+}
+func (rd ResultDataGelbooru) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataGelbooru) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	s.add(SITE_GELBOORU, "https://gelbooru.com/index.php?page=post&s=view&id=%d", rd.GelbooruId)
+	s.addSource(rd.Source)
+	s.addExt(rd.ExtUrls)
+	return s.list()
+}
+
+// 26 Konachan
+type ResultDataKonachan struct {
+	Todo struct{}
+}
+
+func (rd ResultDataKonachan) String() string            { return "[TODO]" }
+func (rd ResultDataKonachan) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataKonachan) CanonicalURLs() []SiteUrl  { return nil }
+
+// 27 Sankaku Channel
+type ResultDataSankaku struct {
+	Todo struct{}
+}
+
+func (rd ResultDataSankaku) String() string            { return "[TODO]" }
+func (rd ResultDataSankaku) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataSankaku) CanonicalURLs() []SiteUrl  { return nil }
+
+// 28 Anime-Pictures.net
+type ResultDataAnimePictures struct {
+	Todo struct{}
+}
+
+func (rd ResultDataAnimePictures) String() string            { return "[TODO]" }
+func (rd ResultDataAnimePictures) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataAnimePictures) CanonicalURLs() []SiteUrl  { return nil }
+
+// 29 e621.net
+type ResultDataE621 struct {
+	Todo struct{}
+}
+
+func (rd ResultDataE621) String() string            { return "[TODO]" }
+func (rd ResultDataE621) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataE621) CanonicalURLs() []SiteUrl  { return nil }
+
+// 30 Idol Complex
+type ResultDataIdolComplex struct {
+	ExtUrls    []string `json:"ext_urls"`
+	IdolId     int      `json:"idol_id"`
+	Creator    string   `json:"creator"` // ""
+	Material   string   `json:"material"`
+	Characters string   `json:"characters"`
+	Source     string   `json:"source"` // ""
+}
+
+func (rd ResultDataIdolComplex) String() string {
+	return fmt.Sprintf(
+		`Creator: %s
+Material: %s
+Characters: %s
+https://www.idolcomplex.com/zh-CN/post/show/%d`,
+		rd.Creator,
+		rd.Material,
+		rd.Characters,
+		rd.IdolId,
+	)
+}
+func (rd ResultDataIdolComplex) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataIdolComplex) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	s.add(SITE_IDOLCOMPLEX, "https://www.idolcomplex.com/post/show/%d", rd.IdolId)
+	s.addSource(rd.Source)
+	s.addExt(rd.ExtUrls)
+	return s.list()
+}
+
+// 31|32
+type ResultDataBcy struct {
+	ExtUrls      []string `json:"ext_urls"`
+	Title        string   `json:"title"`
+	BcyId        int      `json:"bcy_id"`
+	MemberName   string   `json:"member_name"`
+	MemberId     int      `json:"member_id"`
+	MemberLinkId int      `json:"member_link_id"` // "https://bcy.net/illust/detail/{.MemberLinkId}" | "https://bcy.net/coser/detail/{.MemberLinkId}"
+	BcyType      string   `json:"bcy_type"`       // "illust" | "coser"
+}
+
+func (rd ResultDataBcy) String() string {
+	return fmt.Sprintf(
+		`%s
+https://bcy.net/%s/detail/%d
+MemberName: %s
+MemberId: %d`,
+		rd.Title,
+		rd.BcyType,
+		rd.MemberLinkId,
+		rd.MemberName,
+		rd.MemberId,
+	)
+}
+func (rd ResultDataBcy) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataBcy) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	s.add(SITE_BCY, "https://bcy.net/%s/detail/%d", rd.BcyType, rd.MemberLinkId)
+	s.addExt(rd.ExtUrls)
+	return s.list()
+}
+
+// 31 bcy.net Illust
+type ResultDataBcyIllust = ResultDataBcy
+
+// 32 bcy.net Cosplay
+type ResultDataBcyCosplay = ResultDataBcy
+
+// 33 PortalGraphics.net
+type ResultDataPortalGraphics struct {
+	Todo struct{}
+}
+
+func (rd ResultDataPortalGraphics) String() string            { return "[TODO]" }
+func (rd ResultDataPortalGraphics) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataPortalGraphics) CanonicalURLs() []SiteUrl  { return nil }
+
+// 34 deviantArt
+type ResultDataDeviantArt struct {
+	ExtUrls    []string `json:"ext_urls"`
+	Title      string   `json:"title"`
+	DaId       string   `json:"da_id"`
+	AuthorName string   `json:"author_name"`
+	AuthorUrl  string   `json:"author_url"`
+}
+
+func (rd ResultDataDeviantArt) String() string {
+	return fmt.Sprintf(
+		`%s
+https://www.deviantart.com/view/%s
+%s: %s`,
+		rd.Title,
+		rd.DaId,
+		rd.AuthorName,
+		rd.AuthorUrl,
+	)
+}
+func (rd ResultDataDeviantArt) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataDeviantArt) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	s.add(SITE_DEVIANTART, "https://www.deviantart.com/view/%s", rd.DaId)
+	s.addRaw(SITE_DEVIANTART, rd.AuthorUrl)
+	s.addExt(rd.ExtUrls)
+	return s.list()
+}
+
+// 35 Pawoo.net
+type ResultDataPawoo struct {
+	ExtUrls              []string `json:"ext_urls"`
+	CreatedAt            string   `json:"created_at"`
+	PawooId              int      `json:"pawoo_id"`
+	PawooUserAcct        string   `json:"pawoo_user_acct"`
+	PawooUserUsername    string   `json:"pawoo_user_username"`
+	PawooUserDisplayName string   `json:"pawoo_user_display_name"`
+}
+
+func (rd ResultDataPawoo) String() string {
+	return fmt.Sprintf(
+		`%s
+https://pawoo.net/@%s`,
+		rd.CreatedAt,
+		rd.PawooUserAcct,
+	)
+}
+func (rd ResultDataPawoo) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataPawoo) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	s.add(SITE_PAWOO, "https://pawoo.net/@%s/%d", rd.PawooUserAcct, rd.PawooId)
+	s.add(SITE_PAWOO, "https://pawoo.net/@%s", rd.PawooUserAcct)
+	s.addExt(rd.ExtUrls)
+	return s.list()
+}
+
+// 36 Madokami (Manga)
+type ResultDataMadokami struct {
+	Source string `json:"source"`
+	Part   string `json:"part"`
+	Type   string `json:"type"`
+}
+
+func (rd ResultDataMadokami) String() string {
+	return fmt.Sprintf(
+		`Source: %s
+Part: %s
+Type: %s`,
+		rd.Source,
+		rd.Part,
+		rd.Type,
+	)
+}
+func (rd ResultDataMadokami) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataMadokami) CanonicalURLs() []SiteUrl  { return nil }
+
+// 37 MangaDex
+type ResultDataMangaDex struct {
+	ExtUrls []string `json:"ext_urls"`
+	Source  string   `json:"source"` // 作品
+	MdId    string   `json:"md_id"`  // "https://mangadex.org/chapter/{.MdId}"
+	MuId    int      `json:"mu_id"`  // "https://www.mangaupdates.com/series.html?id={.MuId}"
+	MalId   int      `json:"mal_id"` // "https://myanimelist.net/manga/{.MalId}"
+	Part    string   `json:"part"`
+	Artist  string   `json:"artist"`
+	Author  string   `json:"author"`
+}
+
+func (rd ResultDataMangaDex) String() string {
+	return fmt.Sprintf(
+		`%s%s
+https://mangadex.org/chapter/%s
+https://www.mangaupdates.com/series.html?id=%d
+https://myanimelist.net/manga/%d
+Artist: %s
+Author: %s`,
+		rd.Source,
+		rd.Part,
+		rd.MdId,
+		rd.MuId,
+		rd.MalId,
+		rd.Artist,
+		rd.Author,
+	)
+}
+func (rd ResultDataMangaDex) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataMangaDex) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	s.add(SITE_MANGADEX, "https://mangadex.org/chapter/%s", rd.MdId)
+	s.add(SITE_MANGAUPDATES, "https://www.mangaupdates.com/series.html?id=%d", rd.MuId)
+	s.add(SITE_MYANIMELIST, "https://myanimelist.net/manga/%d", rd.MalId)
+	s.addExt(rd.ExtUrls)
+	return s.list()
+}
+
+// 38 H-Misc (eH)
+type ResultDataEHentai = ResultDataDoujin
+
+// 39 ArtStation
+type ResultDataArtStation struct {
+	ExtUrls    []string `json:"ext_urls"`
+	Title      string   `json:"title"`
+	AsProject  string   `json:"as_project"`
+	AuthorName string   `json:"author_name"`
+	AuthorUrl  string   `json:"author_url"`
+}
+
+func (rd ResultDataArtStation) String() string {
+	return fmt.Sprintf(
+		`%s
+https://www.artstation.com/artwork/%s
+%s: %s`,
+		rd.Title,
+		rd.AsProject,
+		rd.AuthorName,
+		rd.AuthorUrl,
+	)
+}
+func (rd ResultDataArtStation) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataArtStation) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	s.add(SITE_ARTSTATION, "https://www.artstation.com/artwork/%s", rd.AsProject)
+	s.addRaw(SITE_ARTSTATION, rd.AuthorUrl)
+	s.addExt(rd.ExtUrls)
+	return s.list()
+}
+
+// 40 FurAffinity
+type ResultDataFurAffinity struct {
+	ExtUrls    []string `json:"ext_urls"`
+	Title      string   `json:"title"`
+	FaId       int      `json:"fa_id"`
+	AuthorName string   `json:"author_name"`
+	AuthorUrl  string   `json:"author_url"`
+}
+
+func (rd ResultDataFurAffinity) String() string {
+	return fmt.Sprintf(
+		`%s
+Author: %s
+https://www.furaffinity.net/view/%d
+%s`,
+		rd.Title,
+		rd.AuthorName,
+		rd.FaId,
+		rd.AuthorUrl,
+	)
+}
+func (rd ResultDataFurAffinity) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataFurAffinity) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	s.add(SITE_FURAFFINITY, "https://www.furaffinity.net/view/%d", rd.FaId)
+	s.addRaw(SITE_FURAFFINITY, rd.AuthorUrl)
+	s.addExt(rd.ExtUrls)
+	return s.list()
+}
+
+// 41 Twitter
+type ResultDataTwitter struct {
+	ExtUrls           []string `json:"ext_urls"`
+	CreatedAt         string   `json:"created_at"` // "2019-07-18T16:09:17Z"
+	TweetId           string   `json:"tweet_id"`   // https://x.com/i/web/status/{.TweetId}
+	TwitterUserId     string   `json:"twitter_user_id"`
+	TwitterUserHandle string   `json:"twitter_user_handle"` // https://x.com/{.TwitterUserHandle}
+}
+
+func (rd ResultDataTwitter) String() string {
+	return fmt.Sprintf(
+		`%s
+https://x.com/%s/status/%s
+https://x.com/intent/user?user_id=%s`,
+		formatTime(rd.CreatedAt),
+		rd.TwitterUserHandle,
+		rd.TweetId,
+		rd.TwitterUserId,
+	)
+}
+func (rd ResultDataTwitter) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataTwitter) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	if rd.TwitterUserHandle != "" {
+		s.add(SITE_TWITTER, "https://x.com/%s/status/%s", rd.TwitterUserHandle, rd.TweetId)
+		s.add(SITE_TWITTER, "https://x.com/%s", rd.TwitterUserHandle)
+	}
+	if rd.TwitterUserHandle == "" {
+		s.add(SITE_TWITTER, "https://x.com/i/web/status/%s", rd.TweetId)
+		s.add(SITE_TWITTER, "https://x.com/intent/user?user_id=%s", rd.TwitterUserId)
+	}
+	s.addExt(rd.ExtUrls)
+	return s.list()
+}
+
+// 42 Furry Network
+type ResultDataFurryNetwork struct {
+	Todo struct{}
+}
+
+func (rd ResultDataFurryNetwork) String() string            { return "[TODO]" }
+func (rd ResultDataFurryNetwork) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataFurryNetwork) CanonicalURLs() []SiteUrl  { return nil }
+
+// 43 Kemono
+type ResultDataKemono struct {
+	ExtUrls     []string `json:"ext_urls"`
+	Published   string   `json:"published"` // "2020-09-25T01:34:37.000Z"
+	Title       string   `json:"title"`
+	Service     string   `json:"service"`      // "fanbox"
+	ServiceName string   `json:"service_name"` // "pixiv FANBOX"
+	Id          string   `json:"id"`
+	UserId      string   `json:"user_id"` // "https://www.pixiv.net/fanbox/creator/{.UserId}/post/{.Id}"
+	UserName    string   `json:"user_name"`
+}
+
+func (rd ResultDataKemono) String() string {
+	return fmt.Sprintf(
+		`%s
+https://www.pixiv.net/fanbox/creator/%s/post/%s
+%s: https://www.pixiv.net/fanbox/creator/%s`,
+		rd.Title,
+		rd.UserId,
+		rd.Id,
+		rd.UserName,
+		rd.UserId,
+	)
+}
+func (rd ResultDataKemono) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataKemono) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	if rd.Service == "fanbox" {
+		s.add(SITE_FANBOX, "https://www.pixiv.net/fanbox/creator/%s/post/%s", rd.UserId, rd.Id)
+		s.add(SITE_FANBOX, "https://www.pixiv.net/fanbox/creator/%s", rd.UserId)
+	}
+	s.add(SITE_KEMONO, "https://kemono.su/%s/user/%s/post/%s", rd.Service, rd.UserId, rd.Id)
+	s.addExt(rd.ExtUrls)
+	return s.list()
+}
+
+// 44 Skeb
+type ResultDataSkeb struct {
+	ExtUrls     []string `json:"ext_urls"`
+	Path        string   `json:"path"`         // "/@neko_satsuma/works/21"
+	Creator     string   `json:"creator"`      // "@neko_satsuma"
+	CreatorName string   `json:"creator_name"` // "\u306d\u3053\u3055\u3064\u307e"
+	AuthorName  string   `json:"author_name"`  // null
+	AuthorUrl   string   `json:"author_url"`   // "https://skeb.jp/@neko_satsuma"
+}
+
+func (rd ResultDataSkeb) String() string {
+	return fmt.Sprintf(
+		`https://skeb.jp%s
+%s: https://skeb.jp/%s`,
+		rd.Path,
+		rd.CreatorName,
+		rd.Creator,
+	)
+}
+func (rd ResultDataSkeb) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultDataSkeb) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+	s.add(SITE_SKEB, "https://skeb.jp%s", rd.Path)
+	s.add(SITE_SKEB, "https://skeb.jp/%s", rd.Creator)
+	s.addExt(rd.ExtUrls)
+	return s.list()
+}
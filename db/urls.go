@@ -0,0 +1,185 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+type Site string
+
+const (
+	SITE_UNKNOWN       Site = ""
+	SITE_PIXIV         Site = "pixiv"
+	SITE_FANBOX        Site = "fanbox"
+	SITE_SEIGA         Site = "seiga"
+	SITE_DANBOORU      Site = "danbooru"
+	SITE_GELBOORU      Site = "gelbooru"
+	SITE_DRAWR         Site = "drawr"
+	SITE_NIJIE         Site = "nijie"
+	SITE_YANDERE       Site = "yandere"
+	SITE_FAKKU         Site = "fakku"
+	SITE_NHENTAI       Site = "nhentai"
+	SITE_EHENTAI       Site = "ehentai"
+	SITE_GETCHU        Site = "getchu"
+	SITE_ANIDB         Site = "anidb"
+	SITE_ANILIST       Site = "anilist"
+	SITE_MYANIMELIST   Site = "myanimelist"
+	SITE_IMDB          Site = "imdb"
+	SITE_KONACHAN      Site = "konachan"
+	SITE_SANKAKU       Site = "sankaku"
+	SITE_ANIMEPICTURES Site = "anime-pictures"
+	SITE_E621          Site = "e621"
+	SITE_IDOLCOMPLEX   Site = "idolcomplex"
+	SITE_BCY           Site = "bcy"
+	SITE_DEVIANTART    Site = "deviantart"
+	SITE_PAWOO         Site = "pawoo"
+	SITE_MANGADEX      Site = "mangadex"
+	SITE_MANGAUPDATES  Site = "mangaupdates"
+	SITE_ARTSTATION    Site = "artstation"
+	SITE_FURAFFINITY   Site = "furaffinity"
+	SITE_TWITTER       Site = "twitter"
+	SITE_FURRYNETWORK  Site = "furrynetwork"
+	SITE_KEMONO        Site = "kemono"
+	SITE_SKEB          Site = "skeb"
+	SITE_LOFTER        Site = "lofter"
+	SITE_WEIBO         Site = "weibo"
+)
+
+// siteDomains 域名 (含子域名) 到站点的映射
+var siteDomains = map[string]Site{
+	"pixiv.net":          SITE_PIXIV,
+	"pximg.net":          SITE_PIXIV,
+	"fanbox.cc":          SITE_FANBOX,
+	"seiga.nicovideo.jp": SITE_SEIGA,
+	"donmai.us":          SITE_DANBOORU,
+	"gelbooru.com":       SITE_GELBOORU,
+	"drawr.net":          SITE_DRAWR,
+	"nijie.info":         SITE_NIJIE,
+	"yande.re":           SITE_YANDERE,
+	"fakku.net":          SITE_FAKKU,
+	"nhentai.net":        SITE_NHENTAI,
+	"e-hentai.org":       SITE_EHENTAI,
+	"exhentai.org":       SITE_EHENTAI,
+	"getchu.com":         SITE_GETCHU,
+	"anidb.net":          SITE_ANIDB,
+	"anilist.co":         SITE_ANILIST,
+	"myanimelist.net":    SITE_MYANIMELIST,
+	"imdb.com":           SITE_IMDB,
+	"konachan.com":       SITE_KONACHAN,
+	"konachan.net":       SITE_KONACHAN,
+	"sankakucomplex.com": SITE_SANKAKU,
+	"anime-pictures.net": SITE_ANIMEPICTURES,
+	"e621.net":           SITE_E621,
+	"idolcomplex.com":    SITE_IDOLCOMPLEX,
+	"bcy.net":            SITE_BCY,
+	"deviantart.com":     SITE_DEVIANTART,
+	"pawoo.net":          SITE_PAWOO,
+	"mangadex.org":       SITE_MANGADEX,
+	"mangaupdates.com":   SITE_MANGAUPDATES,
+	"artstation.com":     SITE_ARTSTATION,
+	"furaffinity.net":    SITE_FURAFFINITY,
+	"twitter.com":        SITE_TWITTER,
+	"x.com":              SITE_TWITTER,
+	"furrynetwork.com":   SITE_FURRYNETWORK,
+	"kemono.su":          SITE_KEMONO,
+	"kemono.party":       SITE_KEMONO,
+	"skeb.jp":            SITE_SKEB,
+	"lofter.com":         SITE_LOFTER,
+	"weibo.com":          SITE_WEIBO,
+	"weibo.cn":           SITE_WEIBO,
+}
+
+// SiteOf 根据域名判断链接所属站点, 未知站点返回去掉 "www." 的域名
+func SiteOf(u *url.URL) Site {
+	if u == nil {
+		return SITE_UNKNOWN
+	}
+	host := strings.ToLower(u.Hostname())
+	for h := host; h != ""; {
+		if site, ok := siteDomains[h]; ok {
+			return site
+		}
+		_, parent, found := strings.Cut(h, ".")
+		if !found {
+			break
+		}
+		h = parent
+	}
+	return Site(strings.TrimPrefix(host, "www."))
+}
+
+// SiteUrl 带站点标签的完整链接
+type SiteUrl struct {
+	Site Site
+	Url  *url.URL
+}
+
+func (su SiteUrl) String() string {
+	if su.Url == nil {
+		return ""
+	}
+	return su.Url.String()
+}
+
+// siteUrls 用于逐条构造 []SiteUrl, 自动跳过零值 id 与重复链接
+type siteUrls []SiteUrl
+
+// add 格式化并添加链接, 任一参数为零值时跳过
+func (s *siteUrls) add(site Site, format string, args ...any) {
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case int:
+			if v == 0 {
+				return
+			}
+		case string:
+			if v == "" {
+				return
+			}
+		}
+	}
+	s.addRaw(site, fmt.Sprintf(format, args...))
+}
+
+// addRaw 添加一条已完整的链接, site 为空时根据域名判断
+func (s *siteUrls) addRaw(site Site, rawUrl string) {
+	rawUrl = strings.TrimSpace(rawUrl)
+	if rawUrl == "" {
+		return
+	}
+	if !strings.Contains(rawUrl, "://") {
+		rawUrl = "https://" + strings.TrimPrefix(rawUrl, "//")
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Host == "" {
+		return
+	}
+	if u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+	if site == SITE_UNKNOWN {
+		site = SiteOf(u)
+	}
+	key := strings.TrimSuffix(u.String(), "/")
+	for _, su := range *s {
+		if strings.TrimSuffix(su.Url.String(), "/") == key {
+			return
+		}
+	}
+	*s = append(*s, SiteUrl{Site: site, Url: u})
+}
+
+// addExt 合并 ext_urls
+func (s *siteUrls) addExt(extUrls []string) {
+	for _, ext := range extUrls {
+		s.addRaw(SITE_UNKNOWN, ext)
+	}
+}
+
+func (s siteUrls) list() []SiteUrl {
+	if len(s) == 0 {
+		return nil
+	}
+	return s
+}
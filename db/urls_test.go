@@ -0,0 +1,118 @@
+package db
+
+import (
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestSiteOf(t *testing.T) {
+	for _, tt := range []struct {
+		url  string
+		want Site
+	}{
+		{"https://www.pixiv.net/artworks/1", SITE_PIXIV},
+		{"https://i.pximg.net/img-original/img/1_p0.png", SITE_PIXIV},
+		{"https://danbooru.donmai.us/posts/1", SITE_DANBOORU},
+		{"https://X.COM/a/status/1", SITE_TWITTER},
+		{"https://mobile.twitter.com/a", SITE_TWITTER},
+		{"https://www.example.com/a", "example.com"},
+		{"https://example.com:8080/a", "example.com"},
+	} {
+		u, _ := url.Parse(tt.url)
+		if got := SiteOf(u); got != tt.want {
+			t.Errorf("SiteOf(%s) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+	if SiteOf(nil) != SITE_UNKNOWN {
+		t.Error("SiteOf(nil) should be unknown")
+	}
+}
+
+func TestCanonicalURLs(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		rd   ResultData
+		want []string
+	}{
+		{
+			"pixiv merges ext_urls without duplicates",
+			ResultDataPixiv{PixivId: 1, MemberId: 2, ExtUrls: []string{"https://www.pixiv.net/artworks/1/", "http://example.com/x"}},
+			[]string{"https://www.pixiv.net/artworks/1", "https://www.pixiv.net/users/2", "https://example.com/x"},
+		},
+		{
+			"pixiv skips zero ids",
+			ResultDataPixiv{MemberId: 2},
+			[]string{"https://www.pixiv.net/users/2"},
+		},
+		{
+			"danbooru without gelbooru id",
+			ResultDataDanbooru{DanbooruId: 3},
+			[]string{"https://danbooru.donmai.us/posts/3"},
+		},
+		{
+			"danbooru with gelbooru id",
+			ResultDataDanbooru{DanbooruId: 3, GelbooruId: 4},
+			[]string{"https://danbooru.donmai.us/posts/3", "https://gelbooru.com/index.php?page=post&s=view&id=4"},
+		},
+		{
+			"bcy needs both type and id",
+			ResultDataBcy{MemberLinkId: 5},
+			nil,
+		},
+		{
+			"twitter with handle",
+			ResultDataTwitter{TweetId: "6", TwitterUserId: "7", TwitterUserHandle: "a"},
+			[]string{"https://x.com/a/status/6", "https://x.com/a"},
+		},
+		{
+			"twitter without handle",
+			ResultDataTwitter{TweetId: "6", TwitterUserId: "7"},
+			[]string{"https://x.com/i/web/status/6", "https://x.com/intent/user?user_id=7"},
+		},
+		{
+			"kemono fanbox",
+			ResultDataKemono{Service: "fanbox", UserId: "8", Id: "9"},
+			[]string{"https://www.pixiv.net/fanbox/creator/8/post/9", "https://www.pixiv.net/fanbox/creator/8", "https://kemono.su/fanbox/user/8/post/9"},
+		},
+		{
+			"kemono other service",
+			ResultDataKemono{Service: "patreon", UserId: "8", Id: "9"},
+			[]string{"https://kemono.su/patreon/user/8/post/9"},
+		},
+		{
+			"deviantart author url without scheme",
+			ResultDataDeviantArt{DaId: "x", AuthorUrl: "//www.deviantart.com/a"},
+			[]string{"https://www.deviantart.com/view/x", "https://www.deviantart.com/a"},
+		},
+		{
+			"empty",
+			ResultDataAnime{},
+			nil,
+		},
+		{
+			"unknown index with ext_urls",
+			ResultDataUnknown{Raw: map[string]any{"ext_urls": []any{"https://yande.re/post/show/1", 2, ""}}},
+			[]string{"https://yande.re/post/show/1"},
+		},
+	} {
+		var got []string
+		for _, su := range tt.rd.CanonicalURLs() {
+			got = append(got, su.String())
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalURLsSite(t *testing.T) {
+	urls := ResultDataDanbooru{DanbooruId: 1, GelbooruId: 2, ExtUrls: []string{"https://www.pixiv.net/artworks/3"}}.CanonicalURLs()
+	var sites []Site
+	for _, su := range urls {
+		sites = append(sites, su.Site)
+	}
+	if want := []Site{SITE_DANBOORU, SITE_GELBOORU, SITE_PIXIV}; !slices.Equal(sites, want) {
+		t.Errorf("sites = %v, want %v", sites, want)
+	}
+}
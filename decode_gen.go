@@ -0,0 +1,88 @@
+// Code generated by gendb from indexes.json; DO NOT EDIT.
+
+package SauceNao
+
+import "github.com/Miuzarte/SauceNAO-go/db"
+
+// decodeIndex 按索引解码 data, 未知索引返回 nil, nil
+func decodeIndex(id db.IndexId, data map[string]any) (db.ResultData, error) {
+	switch id {
+	case db.HMAGAZINES:
+		return decodeTo[db.ResultDataHMagazines](data)
+	case db.HGAMECG:
+		return decodeTo[db.ResultDataHGameCg](data)
+	case db.DOUJINSHIDB:
+		return decodeTo[db.ResultDataDoujinshiDb](data)
+	case db.PIXIV:
+		return decodeTo[db.ResultDataPixiv](data)
+	case db.SEIGA:
+		return decodeTo[db.ResultDataSeiga](data)
+	case db.DANBOORU:
+		return decodeTo[db.ResultDataDanbooru](data)
+	case db.DRAWR:
+		return decodeTo[db.ResultDataDrawr](data)
+	case db.NIJIE:
+		return decodeTo[db.ResultDataNijie](data)
+	case db.YANDERE:
+		return decodeTo[db.ResultDataYandere](data)
+	case db.SHUTTERSTOCK:
+		return decodeTo[db.ResultDataShutterstock](data)
+	case db.FAKKU:
+		return decodeTo[db.ResultDataFakku](data)
+	case db.NHENTAI:
+		return decodeTo[db.ResultDataNHentai](data)
+	case db.MARKET2D:
+		return decodeTo[db.ResultDataMarket2d](data)
+	case db.MEDIBANG:
+		return decodeTo[db.ResultDataMediBang](data)
+	case db.ANIME:
+		return decodeTo[db.ResultDataAnime](data)
+	case db.HANIME:
+		return decodeTo[db.ResultDataHAnime](data)
+	case db.MOVIES:
+		return decodeTo[db.ResultDataMovies](data)
+	case db.SHOWS:
+		return decodeTo[db.ResultDataShows](data)
+	case db.GELBOORU:
+		return decodeTo[db.ResultDataGelbooru](data)
+	case db.KONACHAN:
+		return decodeTo[db.ResultDataKonachan](data)
+	case db.SANKAKU:
+		return decodeTo[db.ResultDataSankaku](data)
+	case db.ANIMEPICTURES:
+		return decodeTo[db.ResultDataAnimePictures](data)
+	case db.E621:
+		return decodeTo[db.ResultDataE621](data)
+	case db.IDOLCOMPLEX:
+		return decodeTo[db.ResultDataIdolComplex](data)
+	case db.BCY_ILLUST:
+		return decodeTo[db.ResultDataBcyIllust](data)
+	case db.BCY_COSPLAY:
+		return decodeTo[db.ResultDataBcyCosplay](data)
+	case db.PORTALGRAPHICS:
+		return decodeTo[db.ResultDataPortalGraphics](data)
+	case db.DEVIANTART:
+		return decodeTo[db.ResultDataDeviantArt](data)
+	case db.PAWOO:
+		return decodeTo[db.ResultDataPawoo](data)
+	case db.MADOKAMI:
+		return decodeTo[db.ResultDataMadokami](data)
+	case db.MANGADEX:
+		return decodeTo[db.ResultDataMangaDex](data)
+	case db.EHENTAI:
+		return decodeTo[db.ResultDataEHentai](data)
+	case db.ARTSTATION:
+		return decodeTo[db.ResultDataArtStation](data)
+	case db.FURAFFINITY:
+		return decodeTo[db.ResultDataFurAffinity](data)
+	case db.TWITTER:
+		return decodeTo[db.ResultDataTwitter](data)
+	case db.FURRYNETWORK:
+		return decodeTo[db.ResultDataFurryNetwork](data)
+	case db.KEMONO:
+		return decodeTo[db.ResultDataKemono](data)
+	case db.SKEB:
+		return decodeTo[db.ResultDataSkeb](data)
+	}
+	return nil, nil
+}
@@ -0,0 +1,150 @@
+package SauceNao
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+var fuzzSeeds = []string{
+	`{"header":{"status":0,"short_remaining":3},"results":[{"header":{"similarity":"90.00","index_id":5},"data":{"ext_urls":["https://www.pixiv.net/artworks/1"],"pixiv_id":1,"member_id":2}}]}`,
+	`{"results":[{"header":{"index_id":41},"data":{"tweet_id":"1","twitter_user_id":"2","created_at":"2020-01-01T00:00:00Z"}}]}`,
+	`{"results":[{"header":{"index_id":18},"data":{"creator":"a","source":"b"}}]}`,
+	`{"results":[{"header":{"index_id":9},"data":{"ext_urls":1,"danbooru_id":"x"}}]}`,
+	`{"results":[{"header":{"index_id":999},"data":null}]}`,
+	`{"results":[{"header":{"index_id":-1},"data":{"ext_urls":[null,{}]}}]}`,
+}
+
+// exercise 调用结果数据的全部方法, 不应 panic
+func exercise(t *testing.T, r Result) {
+	rd := r.DecodeData()
+	if rd == nil {
+		t.Fatalf("DecodeData returned nil for index %d", r.Header.IndexId)
+	}
+	_ = rd.String()
+	_ = rd.Json("  ")
+	_ = rd.CanonicalURLs()
+}
+
+func FuzzResponse(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, body []byte) {
+		var resp Response
+		if json.Unmarshal(body, &resp) != nil {
+			return
+		}
+		for _, r := range resp.Results {
+			exercise(t, r)
+		}
+	})
+}
+
+func FuzzDecodeData(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		var resp Response
+		json.Unmarshal([]byte(s), &resp)
+		for _, r := range resp.Results {
+			data, _ := json.Marshal(r.Data)
+			for id := range 45 {
+				f.Add(id, data)
+			}
+		}
+	}
+	f.Fuzz(func(t *testing.T, id int, data []byte) {
+		var m map[string]any
+		if json.Unmarshal(data, &m) != nil {
+			return
+		}
+		exercise(t, Result{Header: ResultHeader{IndexId: db.IndexId(id)}, Data: m})
+	})
+}
+
+// 对每个有结构体的索引: 随机值 -> json -> DecodeData 得到相同的值, 且 Json() 可解回相同的值
+func TestDecodeDataRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for id := db.IndexId(0); id < 64; id++ {
+		if _, ok := id.Fields(); !ok {
+			continue
+		}
+		typ := reflect.TypeOf(Result{Header: ResultHeader{IndexId: id}}.DecodeData()).Elem()
+		for range 50 {
+			v, ok := quick.Value(typ, rnd)
+			if !ok {
+				t.Fatalf("%d: cannot generate %s", id, typ)
+			}
+			want := v.Interface()
+
+			raw, err := json.Marshal(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 经过 Result 的解析, 超过 2^53 的数字也应保持精确
+			var r Result
+			err = json.Unmarshal(fmt.Appendf(nil, `{"header":{"index_id":%d},"data":%s}`, id, raw), &r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rd := r.DecodeData()
+			if _, unknown := rd.(*db.ResultDataUnknown); unknown {
+				t.Fatalf("%d %s: decode failed: %v", id, id, rd)
+			}
+			if got := reflect.ValueOf(rd).Elem().Interface(); !reflect.DeepEqual(got, want) {
+				t.Fatalf("%d %s: decoded\n%#v\nwant\n%#v", id, id, got, want)
+			}
+
+			back := reflect.New(typ)
+			err = json.Unmarshal([]byte(rd.Json("")), back.Interface())
+			if err != nil {
+				t.Fatalf("%d %s: %v", id, id, err)
+			}
+			if got := back.Elem().Interface(); !reflect.DeepEqual(got, want) {
+				t.Fatalf("%d %s: Json() round trip\n%#v\nwant\n%#v", id, id, got, want)
+			}
+		}
+	}
+}
+
+func TestDecodeDataMalformed(t *testing.T) {
+	for _, data := range []map[string]any{
+		{"pixiv_id": "abc"},
+		{"pixiv_id": []any{1.0}},
+		{"ext_urls": map[string]any{"a": 1.0}},
+	} {
+		rd, ok := Result{Header: ResultHeader{IndexId: db.PIXIV}, Data: data}.DecodeData().(*db.ResultDataUnknown)
+		if !ok || rd.Err == nil || !strings.Contains(rd.Err.Error(), "'pixiv_id'") && !strings.Contains(rd.Err.Error(), "'ext_urls") {
+			t.Errorf("%v: %#v", data, rd)
+		}
+		exercise(t, Result{Header: ResultHeader{IndexId: db.PIXIV}, Data: data})
+	}
+
+	// 没有原始数据时也能输出错误
+	rd := db.ResultDataUnknown{Err: errors.New("broken")}
+	if !strings.Contains(rd.String(), "broken") || rd.Raw != nil {
+		t.Errorf("nil raw: %s", rd.String())
+	}
+}
+
+// Data 中的数字仍为 float64, 大 id 只在解码时保持精确
+func TestDecodeDataLargeId(t *testing.T) {
+	var resp Response
+	err := json.Unmarshal([]byte(`{"results":[{"header":{"index_id":5},"data":{"pixiv_id":9007199254740993,"member_id":2}}]}`), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := resp.Results[0]
+	if _, ok := r.Data["member_id"].(float64); !ok {
+		t.Errorf("member_id is %T, want float64", r.Data["member_id"])
+	}
+	if rd, ok := r.DecodeData().(*db.ResultDataPixiv); !ok || rd.PixivId != 9007199254740993 {
+		t.Errorf("decoded %#v", r.DecodeData())
+	}
+}
@@ -0,0 +1,139 @@
+package SauceNao
+
+import (
+	"time"
+
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+// Hooks 请求生命周期中的回调, 为 nil 的不调用.
+// 回调在发出请求的 goroutine 中同步执行, 不应阻塞; 此时未持有 client 的锁, 可以调用 [Client.Quota] 等方法
+type Hooks struct {
+	OnRequest         func(RequestEvent)
+	OnResponse        func(ResponseEvent)
+	OnQuotaUpdate     func(QuotaEvent)
+	OnChallenge       func(ChallengeEvent)
+	OnChallengeSolved func(ChallengeSolvedEvent)
+	OnRetry           func(RetryEvent)
+	OnDecodeError     func(DecodeErrorEvent)
+	OnSchemaDrift     func(SchemaDriftEvent) // 设置后才会逐条比较, 见 [db.CheckSchema]
+}
+
+// RequestEvent 向 SauceNAO 或 FlareSolverr 发出请求前
+type RequestEvent struct {
+	Upstream string // [UPSTREAM_SAUCENAO] | [UPSTREAM_FLARESOLVERR]
+	Method   string
+	Url      string // 已隐去 api_key
+	Attempt  int    // 从 1 开始, 过 cf 后的重试递增
+}
+
+// ResponseEvent 收到响应或请求失败后
+type ResponseEvent struct {
+	RequestEvent
+	StatusCode int // 请求失败时为 0
+	Duration   time.Duration
+	Err        error // 网络错误, 或 FlareSolverr 未能解决
+}
+
+// QuotaEvent 从响应中得知新的限额, 或因 429 标记限额用尽
+type QuotaEvent struct {
+	Quota    Quota
+	Previous Quota
+	Exceeded bool // 由 429 触发
+}
+
+// ChallengeEvent SauceNAO 返回 403 (cf challenge), 随后尝试经 FlareSolverr 解决
+type ChallengeEvent struct {
+	Url     string
+	Attempt int
+}
+
+// ChallengeSolvedEvent 经 FlareSolverr 尝试解决 cf challenge 后, 失败时 Err 非 nil
+type ChallengeSolvedEvent struct {
+	Url       string
+	UserAgent string
+	Duration  time.Duration
+	Err       error
+}
+
+// RetryEvent 解决 cf challenge 后重试原请求前
+type RetryEvent struct {
+	Url     string
+	Attempt int // 即将进行的尝试
+	Reason  string
+}
+
+// DecodeErrorEvent [Result.DecodeData] 无法解码为对应索引的结构体
+type DecodeErrorEvent struct {
+	IndexId db.IndexId
+	Data    map[string]any
+	Err     error
+}
+
+// SchemaDriftEvent 解析响应时, 某条结果的 data 与对应结构体的字段不一致
+type SchemaDriftEvent struct {
+	db.Drift
+	Similarity string
+}
+
+// hooks client 为 nil 时返回零值
+func (c *Client) hooks() *Hooks {
+	if c == nil {
+		return &Hooks{}
+	}
+	return &c.Hooks
+}
+
+func (h *Hooks) request(ev RequestEvent) {
+	if h.OnRequest != nil {
+		h.OnRequest(ev)
+	}
+}
+
+func (h *Hooks) response(ev ResponseEvent) {
+	if h.OnResponse != nil {
+		h.OnResponse(ev)
+	}
+}
+
+func (h *Hooks) quotaUpdate(ev QuotaEvent) {
+	if h.OnQuotaUpdate != nil {
+		h.OnQuotaUpdate(ev)
+	}
+}
+
+func (h *Hooks) challenge(ev ChallengeEvent) {
+	if h.OnChallenge != nil {
+		h.OnChallenge(ev)
+	}
+}
+
+func (h *Hooks) challengeSolved(ev ChallengeSolvedEvent) {
+	if h.OnChallengeSolved != nil {
+		h.OnChallengeSolved(ev)
+	}
+}
+
+func (h *Hooks) retry(ev RetryEvent) {
+	if h.OnRetry != nil {
+		h.OnRetry(ev)
+	}
+}
+
+func (h *Hooks) decodeError(ev DecodeErrorEvent) {
+	if h.OnDecodeError != nil {
+		h.OnDecodeError(ev)
+	}
+}
+
+func (h *Hooks) schemaDrift(results []Result) {
+	if h.OnSchemaDrift == nil {
+		return
+	}
+	for _, r := range results {
+		d := db.CheckSchema(r.Header.IndexId, r.Data)
+		if !d.Empty() {
+			h.OnSchemaDrift(SchemaDriftEvent{Drift: d, Similarity: r.Header.Similarity})
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package SauceNao
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	forbidden := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if forbidden {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `{"header":{"short_limit":"4","long_limit":"100","short_remaining":3,"long_remaining":9},
+"results":[{"header":{"similarity":"90.00","index_id":5},"data":{"pixiv_id":[1]}}]}`)
+	}))
+	defer srv.Close()
+
+	var (
+		requests  []RequestEvent
+		responses []ResponseEvent
+		quotas    []QuotaEvent
+		challenge []ChallengeEvent
+		solved    []ChallengeSolvedEvent
+		decode    []DecodeErrorEvent
+		drift     []SchemaDriftEvent
+	)
+	c := NewClient("key", srv.URL, 0, HIDE_NONE, nil)
+	c.Hooks = Hooks{
+		OnRequest:         func(ev RequestEvent) { requests = append(requests, ev) },
+		OnResponse:        func(ev ResponseEvent) { responses = append(responses, ev) },
+		OnQuotaUpdate:     func(ev QuotaEvent) { quotas = append(quotas, ev); c.Quota() },
+		OnChallenge:       func(ev ChallengeEvent) { challenge = append(challenge, ev) },
+		OnChallengeSolved: func(ev ChallengeSolvedEvent) { solved = append(solved, ev) },
+		OnDecodeError:     func(ev DecodeErrorEvent) { decode = append(decode, ev) },
+		OnSchemaDrift:     func(ev SchemaDriftEvent) { drift = append(drift, ev) },
+	}
+
+	// 没有 FlareSolverr, 过 cf 失败
+	c.Post(t.Context(), []byte("a"))
+	if len(challenge) != 1 || len(solved) != 1 || solved[0].Err == nil {
+		t.Errorf("challenge = %+v, solved = %+v", challenge, solved)
+	}
+
+	forbidden = false
+	resp, err := c.Post(t.Context(), []byte("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || len(responses) != 2 || responses[1].StatusCode != http.StatusOK || responses[1].Upstream != UPSTREAM_SAUCENAO {
+		t.Errorf("requests = %+v, responses = %+v", requests, responses)
+	}
+	if len(quotas) != 1 || quotas[0].Quota.LongRemaining != 9 || quotas[0].Previous.Known() {
+		t.Errorf("quotas = %+v", quotas)
+	}
+
+	if len(drift) != 1 || len(drift[0].Unknown) != 0 || len(drift[0].Missing) != 4 {
+		t.Errorf("drift = %+v", drift)
+	}
+
+	resp.Results[0].DecodeData()
+	if len(decode) != 1 || decode[0].IndexId != 5 {
+		t.Errorf("decode = %+v", decode)
+	}
+}
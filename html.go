@@ -0,0 +1,194 @@
+package SauceNao
+
+import (
+	"errors"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+var ErrHtmlUnrecognized = errors.New("unrecognized search result page")
+
+var (
+	reHtmlResult      = regexp.MustCompile(`(?s)<div class="result(?: hidden)?"[^>]*>\s*<table class="resulttable">(.*?)</table>`)
+	reHtmlImgTitle    = regexp.MustCompile(`(?s)<img[^>]*\stitle="([^"]*)"`)
+	reHtmlIndexId     = regexp.MustCompile(`Index #(\d+):`)
+	reHtmlImgDataSrc  = regexp.MustCompile(`(?s)<img[^>]*\sdata-src="([^"]*)"`)
+	reHtmlImgSrc      = regexp.MustCompile(`(?s)<img[^>]*\ssrc="([^"]*)"`)
+	reHtmlSimilarity  = regexp.MustCompile(`(?s)<div class="resultsimilarityinfo">\s*([\d.]+)%`)
+	reHtmlMiscInfo    = regexp.MustCompile(`(?s)<div class="resultmiscinfo">(.*?)</div>`)
+	reHtmlImageLink   = regexp.MustCompile(`(?s)<div class="resultimage">\s*<a href="([^"]*)"`)
+	reHtmlTitle       = regexp.MustCompile(`(?s)<div class="resulttitle">(.*?)</div>`)
+	reHtmlColumn      = regexp.MustCompile(`(?s)<div class="resultcontentcolumn">(.*?)</div>`)
+	reHtmlHref        = regexp.MustCompile(`href="([^"]*)"`)
+	reHtmlStrong      = regexp.MustCompile(`(?s)<strong>(.*?)</strong>`)
+	reHtmlTag         = regexp.MustCompile(`(?s)<[^>]*>`)
+	reHtmlTrailingNum = regexp.MustCompile(`(\d+)/?$`)
+)
+
+// htmlLabelKeys 结果页字段名到 api 字段名的映射,
+// 未列出的字段名转小写并将非字母数字替换为 "_", 如 "Pixiv ID" -> "pixiv_id"
+var htmlLabelKeys = map[string]string{
+	"member":      "member_name",
+	"author":      "author_name",
+	"creator(s)":  "creator",
+	"yande.re id": "yandere_id",
+	"twitter":     "twitter_user_handle",
+}
+
+// parseHtmlResponse 解析公开搜索页 (output_type=0) 的结果表格,
+// 尽量填充与 json api 相同的字段
+func parseHtmlResponse(body []byte, resp *Response) error {
+	page := string(body)
+	matches := reHtmlResult.FindAllStringSubmatch(page, -1)
+	if matches == nil && !strings.Contains(page, `id="middle"`) {
+		// 既没有结果也不是结果页, 比如 cf 或错误页面
+		return ErrHtmlUnrecognized
+	}
+
+	// 低相似度的结果带有 hidden class, 只是默认折叠, 与露骨内容无关
+	for _, m := range matches {
+		block := m[1]
+		r := Result{Data: map[string]any{}}
+
+		if t := reHtmlImgTitle.FindStringSubmatch(block); t != nil {
+			r.Header.IndexName = html.UnescapeString(t[1])
+			if id := reHtmlIndexId.FindStringSubmatch(r.Header.IndexName); id != nil {
+				n, _ := strconv.Atoi(id[1])
+				r.Header.IndexId = db.IndexId(n)
+			}
+		}
+		if src := reHtmlImgSrc.FindStringSubmatch(block); src != nil {
+			r.Header.Thumbnail = html.UnescapeString(src[1])
+			// 露骨内容的缩略图会被替换为站内静态图片, 原图放在 data-src, 尚未与真实页面核对
+			if strings.Contains(src[1], "images/static/") {
+				r.Header.Hidden = 1
+			}
+		}
+		if src := reHtmlImgDataSrc.FindStringSubmatch(block); src != nil {
+			r.Header.Thumbnail = html.UnescapeString(src[1])
+		}
+		if sim := reHtmlSimilarity.FindStringSubmatch(block); sim != nil {
+			r.Header.Similarity = sim[1]
+		}
+
+		var extUrls []any
+		addExt := func(u string) {
+			u = html.UnescapeString(u)
+			for _, e := range extUrls {
+				if e == u {
+					return
+				}
+			}
+			extUrls = append(extUrls, u)
+		}
+		if misc := reHtmlMiscInfo.FindStringSubmatch(block); misc != nil {
+			for _, href := range reHtmlHref.FindAllStringSubmatch(misc[1], -1) {
+				addExt(href[1])
+			}
+		}
+		if extUrls == nil {
+			if link := reHtmlImageLink.FindStringSubmatch(block); link != nil {
+				addExt(link[1])
+			}
+		}
+		if extUrls != nil {
+			r.Data["ext_urls"] = extUrls
+		}
+
+		if title := reHtmlTitle.FindStringSubmatch(block); title != nil {
+			if !parseHtmlFields(title[1], r.Data) {
+				r.Data["title"] = htmlText(title[1])
+			}
+		}
+		for _, col := range reHtmlColumn.FindAllStringSubmatch(block, -1) {
+			parseHtmlFields(col[1], r.Data)
+		}
+
+		resp.Results = append(resp.Results, r)
+	}
+
+	resp.Header.ResultsReturned = len(resp.Results)
+	return nil
+}
+
+// parseHtmlFields 解析 "<strong>Label: </strong>value<br />" 形式的字段,
+// 返回是否解析到了字段
+func parseHtmlFields(s string, data map[string]any) bool {
+	var locs [][]int
+	for _, loc := range reHtmlStrong.FindAllStringSubmatchIndex(s, -1) {
+		// 不以冒号结尾的 <strong> 是标题等正文
+		if strings.HasSuffix(strings.TrimSpace(htmlText(s[loc[2]:loc[3]])), ":") {
+			locs = append(locs, loc)
+		}
+	}
+	for i, loc := range locs {
+		label := strings.TrimSuffix(strings.TrimSpace(htmlText(s[loc[2]:loc[3]])), ":")
+		end := len(s)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		raw := s[loc[1]:end]
+		value := htmlText(raw)
+		var href string
+		if h := reHtmlHref.FindStringSubmatch(raw); h != nil {
+			href = html.UnescapeString(h[1])
+		}
+
+		key := htmlLabelKey(label)
+		switch key {
+		case "member_name":
+			data[key] = value
+			if m := reHtmlTrailingNum.FindStringSubmatch(href); m != nil {
+				data["member_id"] = m[1]
+			} else if u, err := url.Parse(href); err == nil && u.Query().Get("id") != "" {
+				data["member_id"] = u.Query().Get("id")
+			}
+		case "author_name":
+			data[key] = value
+			if href != "" {
+				data["author_url"] = href
+			}
+		case "twitter_user_handle":
+			data[key] = strings.TrimPrefix(value, "@")
+		case "source":
+			if href != "" {
+				data[key] = href
+			} else {
+				data[key] = value
+			}
+		default:
+			data[key] = value
+		}
+	}
+	return len(locs) > 0
+}
+
+func htmlLabelKey(label string) string {
+	label = strings.ToLower(label)
+	if key, ok := htmlLabelKeys[label]; ok {
+		return key
+	}
+	var sb strings.Builder
+	for _, r := range label {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			sb.WriteRune(r)
+		} else if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "_") {
+			sb.WriteByte('_')
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "_")
+}
+
+// htmlText 去除标签并反转义
+func htmlText(s string) string {
+	s = strings.ReplaceAll(s, "<br />", "\n")
+	s = strings.ReplaceAll(s, "<br/>", "\n")
+	s = strings.ReplaceAll(s, "<br>", "\n")
+	s = reHtmlTag.ReplaceAllString(s, "")
+	return strings.TrimSpace(html.UnescapeString(s))
+}
@@ -0,0 +1,119 @@
+package SauceNao
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+// testdata/search.html 与 search_empty.html 尚未与抓取的 search.php (output_type=0) 页面核对,
+// 包括以 images/static/ 判断 hidden 的规则, 有真实页面后应脱敏替换
+func TestParseHtmlResponse(t *testing.T) {
+	body, err := os.ReadFile("testdata/search.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := &Response{}
+	err = parseHtmlResponse(body, resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 3 || resp.Header.ResultsReturned != 3 {
+		t.Fatalf("got %d results, want 3", len(resp.Results))
+	}
+
+	pixiv := resp.Results[0]
+	if pixiv.Header.IndexId != db.PIXIV || pixiv.Header.Similarity != "96.12" || pixiv.Header.IsHidden() {
+		t.Errorf("pixiv header = %+v", pixiv.Header)
+	}
+	if pixiv.Header.Thumbnail != "https://img1.saucenao.com/res/pixiv/7890/78901234_p0.jpg?auth=abc&exp=1700000000" {
+		t.Errorf("pixiv thumbnail = %s", pixiv.Header.Thumbnail)
+	}
+	pd, ok := pixiv.DecodeData().(*db.ResultDataPixiv)
+	if !ok {
+		t.Fatalf("pixiv data = %T", pixiv.DecodeData())
+	}
+	if pd.PixivId != 78901234 || pd.MemberId != 1234567 || pd.MemberName != "some_artist" || pd.Title != "Blue Sky & Sea" {
+		t.Errorf("pixiv data = %+v", pd)
+	}
+	if len(pd.ExtUrls) != 1 || pd.ExtUrls[0] != "https://www.pixiv.net/member_illust.php?mode=medium&illust_id=78901234" {
+		t.Errorf("pixiv ext_urls = %v", pd.ExtUrls)
+	}
+
+	danbooru := resp.Results[1]
+	if danbooru.Header.IndexId != db.DANBOORU || !danbooru.Header.IsHidden() {
+		t.Errorf("danbooru header = %+v", danbooru.Header)
+	}
+	if danbooru.Header.Thumbnail != "https://img3.saucenao.com/booru/0/1/0123456789abcdef_2.jpg" {
+		t.Errorf("danbooru thumbnail = %s", danbooru.Header.Thumbnail)
+	}
+	dd, ok := danbooru.DecodeData().(*db.ResultDataDanbooru)
+	if !ok {
+		t.Fatalf("danbooru data = %T", danbooru.DecodeData())
+	}
+	if dd.Creator != "some artist" || dd.Material != "blue archive" || dd.Characters != "miyako (blue archive)" ||
+		dd.Source != "https://i.pximg.net/img-original/img/2020/01/02/03/04/05/78901234_p0.png" || len(dd.ExtUrls) != 2 {
+		t.Errorf("danbooru data = %+v", dd)
+	}
+
+	twitter := resp.Results[2]
+	td, ok := twitter.DecodeData().(*db.ResultDataTwitter)
+	if !ok {
+		t.Fatalf("twitter data = %T", twitter.DecodeData())
+	}
+	if td.TweetId != "1151871637316501504" || td.TwitterUserHandle != "some_artist" {
+		t.Errorf("twitter data = %+v", td)
+	}
+
+	if works := resp.Works(); len(works) != 2 {
+		t.Errorf("len(works) = %d, want 2", len(works))
+	}
+}
+
+func TestParseHtmlResponseEmpty(t *testing.T) {
+	body, err := os.ReadFile("testdata/search_empty.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := &Response{}
+	err = parseHtmlResponse(body, resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 0 {
+		t.Errorf("got %d results, want 0", len(resp.Results))
+	}
+
+	err = parseHtmlResponse([]byte("<html>Just a moment...</html>"), &Response{})
+	if err != ErrHtmlUnrecognized {
+		t.Errorf("err = %v, want %v", err, ErrHtmlUnrecognized)
+	}
+}
+
+func TestHtmlFallbackPost(t *testing.T) {
+	page, err := os.ReadFile("testdata/search.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("output_type") != "0" || r.URL.Query().Has("api_key") {
+			http.Error(w, "unexpected query "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		w.Write(page)
+	}))
+	defer srv.Close()
+
+	client := NewClient("", srv.URL, 0, HIDE_NONE, nil)
+	client.HtmlFallback = true
+	resp, err := client.Post(t.Context(), []byte("image"), WithSafeFilter(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 2 {
+		t.Errorf("got %d results, want 2 after safe filter", len(resp.Results))
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const HEADER = "// Code generated by gendb from indexes.json; DO NOT EDIT.\n\n"
+
+var funcs = template.FuncMap{
+	"quote": strconv.Quote,
+	"join":  strings.Join,
+}
+
+var indexesTmpl = template.Must(template.New("indexes").Funcs(funcs).Parse(`package db
+
+import "reflect"
+
+const (
+{{- range .}}
+	{{.Const}} IndexId = {{.Id}} // {{.Name}}
+{{- end}}
+)
+
+var dbIdToName = [...]string{
+{{- range .}}
+	{{.Const}}: {{quote .Name}},
+{{- end}}
+}
+
+var dbIdToType = [...]reflect.Type{
+{{- range .}}
+	{{.Const}}: reflect.TypeFor[*ResultData{{.Type}}](),
+{{- end}}
+}
+`))
+
+var structsTmpl = template.Must(template.New("structs").Funcs(funcs).Parse(`package db
+{{if .Imports}}
+import (
+{{- range .Imports}}
+	{{quote .}}
+{{- end}}
+)
+{{end}}
+{{- range .Indexes}}
+{{- if .Todo}}
+// {{.Id}} {{.Name}}
+type ResultData{{.Type}} struct {
+	Todo struct{}
+}
+
+func (rd ResultData{{.Type}}) String() string { return "[TODO]" }
+func (rd ResultData{{.Type}}) Json(indent string) string { return toJsonString(rd, indent) }
+func (rd ResultData{{.Type}}) CanonicalURLs() []SiteUrl { return nil }
+{{else if .Fields}}
+// {{.Comment}}
+type ResultData{{.StructName}} struct {
+{{- range .Fields}}
+	{{.Name}} {{.Type}} ` + "`json:\"{{.Json}}\"`" + `{{if .Comment}} // {{.Comment}}{{end}}
+{{- end}}
+}
+
+func (rd ResultData{{.StructName}}) String() string {
+	return fmt.Sprintf(
+		` + "`{{.Format}}`" + `,
+{{- range .Args}}
+		{{.}},
+{{- end}}
+	)
+}
+func (rd ResultData{{.StructName}}) Json(indent string) string { return toJsonString(rd, indent) }
+{{- if .Canonical}}
+func (rd ResultData{{.StructName}}) CanonicalURLs() []SiteUrl {
+	var s siteUrls
+{{- range .Canonical}}
+	{{.}}
+{{- end}}
+	return s.list()
+}
+{{else}}
+func (rd ResultData{{.StructName}}) CanonicalURLs() []SiteUrl { return nil }
+{{end}}
+{{- end}}
+{{- if .Struct}}
+// {{.Id}} {{.Name}}
+type ResultData{{.Type}} = ResultData{{.Struct}}
+{{end}}
+{{- end}}
+`))
+
+var decodeTmpl = template.Must(template.New("decode").Funcs(funcs).Parse(`package SauceNao
+
+import "github.com/Miuzarte/SauceNAO-go/db"
+
+// decodeIndex 按索引解码 data, 未知索引返回 nil, nil
+func decodeIndex(id db.IndexId, data map[string]any) (db.ResultData, error) {
+	switch id {
+{{- range .}}
+	case db.{{.Const}}:
+		return decodeTo[db.ResultData{{.Type}}](data)
+{{- end}}
+	}
+	return nil, nil
+}
+`))
+
+// structView 传给 structsTmpl 的单个结构体
+type structView struct {
+	Index
+	StructName string
+	Comment    string   // 结构体的注释, 共用时列出全部索引
+	Format     string   // String() 的格式
+	Args       []string // String() 的参数
+	Canonical  []string // CanonicalURLs() 中的语句
+}
+
+func sortedSpec(spec []Index) []Index {
+	spec = slices.Clone(spec)
+	slices.SortFunc(spec, func(a, b Index) int { return a.Id - b.Id })
+	return spec
+}
+
+func genIndexes(spec []Index) ([]byte, error) {
+	return render(indexesTmpl, sortedSpec(spec))
+}
+
+func genDecode(spec []Index) ([]byte, error) {
+	return render(decodeTmpl, sortedSpec(spec))
+}
+
+func genStructs(spec []Index) ([]byte, error) {
+	spec = sortedSpec(spec)
+	shared := map[string][]string{} // 共用结构体 -> 索引 id
+	for _, idx := range spec {
+		if idx.Struct != "" {
+			shared[idx.Struct] = append(shared[idx.Struct], strconv.Itoa(idx.Id))
+		}
+	}
+
+	var views []structView
+	var code []string // 用于判断需要的 import
+	hasStruct := false
+	for _, idx := range spec {
+		if !idx.generated() {
+			continue
+		}
+		v := structView{Index: idx, StructName: idx.structName()}
+		if len(idx.Fields) == 0 {
+			views = append(views, v)
+			continue
+		}
+		v.Comment = fmt.Sprintf("%d %s", idx.Id, idx.Name)
+		if ids := shared[idx.Struct]; len(ids) > 0 {
+			v.Comment = strings.Join(ids, "|")
+		}
+
+		if idx.String != "" {
+			v.Format, v.Args, _ = idx.parseTemplate()
+		} else {
+			var lines []string
+			for _, f := range idx.Fields {
+				if f.Json == "ext_urls" && f.Type == "[]string" {
+					continue
+				}
+				verb, arg := fieldVerb(&f)
+				lines = append(lines, f.Name+": "+verb)
+				v.Args = append(v.Args, arg)
+			}
+			if f := idx.field("ext_urls"); f != nil && f.Type == "[]string" {
+				verb, arg := fieldVerb(f)
+				lines = append(lines, verb)
+				v.Args = append(v.Args, arg)
+			}
+			v.Format = strings.Join(lines, "\n")
+		}
+
+		// 相邻且条件相同的链接放在同一个 if 中
+		cond := ""
+		for _, u := range idx.Urls {
+			if u.If != cond {
+				if cond != "" {
+					v.Canonical = append(v.Canonical, "}")
+				}
+				if u.If != "" {
+					expr, _ := idx.condition(u.If)
+					v.Canonical = append(v.Canonical, "if "+expr+" {")
+				}
+				cond = u.If
+			}
+			v.Canonical = append(v.Canonical, urlStmt(u))
+		}
+		if cond != "" {
+			v.Canonical = append(v.Canonical, "}")
+		}
+		if f := idx.field("ext_urls"); f != nil && f.Type == "[]string" {
+			v.Canonical = append(v.Canonical, fmt.Sprintf("s.addExt(rd.%s)", f.Name))
+		}
+		code = append(code, v.Args...)
+		hasStruct = true
+		views = append(views, v)
+	}
+
+	var imports []string
+	for _, pkg := range []string{"cmp", "fmt", "strings"} {
+		used := pkg == "fmt" && hasStruct || slices.ContainsFunc(code, func(c string) bool {
+			return strings.Contains(c, pkg+".")
+		})
+		if used {
+			imports = append(imports, pkg)
+		}
+	}
+	return render(structsTmpl, map[string]any{"Imports": imports, "Indexes": views})
+}
+
+// urlStmt CanonicalURLs 中添加一条链接的语句
+func urlStmt(u Url) string {
+	switch {
+	case u.Raw != "":
+		return fmt.Sprintf("s.addRaw(%s, rd.%s)", u.Site, u.Raw)
+	case u.Source != "":
+		return fmt.Sprintf("s.addSource(rd.%s)", u.Source)
+	}
+	args := []string{u.Site, strconv.Quote(u.Format)}
+	for _, name := range u.Fields {
+		args = append(args, "rd."+name)
+	}
+	return fmt.Sprintf("s.add(%s)", strings.Join(args, ", "))
+}
+
+func render(t *template.Template, data any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(HEADER)
+	err := t.Execute(&buf, data)
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w\n%s", t.Name(), err, buf.Bytes())
+	}
+	return src, nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// 修改 indexes.json 或生成器后需运行 go generate ./db
+func TestGeneratedUpToDate(t *testing.T) {
+	spec, err := loadSpec("../../db/indexes.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, gen := range map[string]func([]Index) ([]byte, error){
+		"../../db/indexes_gen.go": genIndexes,
+		"../../db/structs_gen.go": genStructs,
+		"../../decode_gen.go":     genDecode,
+	} {
+		want, err := gen(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is stale, run go generate ./db", path)
+		}
+	}
+}
+
+func TestGenStructs(t *testing.T) {
+	spec := []Index{{
+		Id: 45, Const: "EXAMPLE", Name: "Example", Type: "Example",
+		Fields: []Field{
+			{Name: "ExtUrls", Json: "ext_urls", Type: "[]string"},
+			{Name: "ExampleId", Json: "example_id", Type: "int", Comment: "id"},
+		},
+		Urls: []Url{{Site: "SITE_UNKNOWN", Format: "https://example.com/%d", Fields: []string{"ExampleId"}}},
+	}}
+	if err := validateSpec(spec); err != nil {
+		t.Fatal(err)
+	}
+	src, err := genStructs(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"ExampleId int      `json:\"example_id\"` // id",
+		`s.add(SITE_UNKNOWN, "https://example.com/%d", rd.ExampleId)`,
+		"s.addExt(rd.ExtUrls)",
+		"\"strings\"",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("missing %q in\n%s", want, src)
+		}
+	}
+
+	spec[0].Urls[0].Fields = []string{"Missing"}
+	if validateSpec(spec) == nil {
+		t.Error("url with unknown field should fail validation")
+	}
+}
+
+func TestGenStructsTemplate(t *testing.T) {
+	spec := []Index{{
+		Id: 45, Const: "EXAMPLE", Name: "Example", Type: "Example", Struct: "Shared",
+		Fields: []Field{
+			{Name: "Title", Json: "title", Type: "string"},
+			{Name: "AltTitle", Json: "alt_title", Type: "string"},
+			{Name: "PostId", Json: "post_id", Type: "int"},
+			{Name: "AuthorUrl", Json: "author_url", Type: "string"},
+			{Name: "Source", Json: "source", Type: "string"},
+		},
+		String: `{or Title AltTitle} 100%{optional "\n#%d" PostId}`,
+		Urls: []Url{
+			{Site: "SITE_UNKNOWN", Format: "https://example.com/%d", Fields: []string{"PostId"}, If: "Title"},
+			{Site: "SITE_UNKNOWN", Raw: "AuthorUrl", If: "Title"},
+			{Source: "Source", If: `Title == "x"`},
+		},
+	}, {
+		Id: 46, Const: "EXAMPLE2", Name: "Example 2", Type: "Example2", Struct: "Shared",
+	}}
+	if err := validateSpec(spec); err != nil {
+		t.Fatal(err)
+	}
+	src, err := genStructs(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"// 45|46\ntype ResultDataShared struct",
+		"`%s 100%%%s`,\n\t\tcmp.Or(rd.Title, rd.AltTitle),\n\t\toptional(\"\\n#%d\", rd.PostId),",
+		"if rd.Title != \"\" {\n\t\ts.add(SITE_UNKNOWN, \"https://example.com/%d\", rd.PostId)\n\t\ts.addRaw(SITE_UNKNOWN, rd.AuthorUrl)\n\t}",
+		"if rd.Title == \"x\" {\n\t\ts.addSource(rd.Source)\n\t}",
+		"type ResultDataExample = ResultDataShared",
+		"type ResultDataExample2 = ResultDataShared",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("missing %q in\n%s", want, src)
+		}
+	}
+
+	for _, broken := range []func(s []Index){
+		func(s []Index) { s[0].String = "{Missing}" },
+		func(s []Index) { s[0].String = "{upper Title}" },
+		func(s []Index) { s[0].Urls[0].If = "!Missing" },
+		func(s []Index) { s[0].Urls[1].Source = "Source" },
+		func(s []Index) { s[1].Struct = "" },
+		func(s []Index) { s[1].Fields = s[0].Fields },
+	} {
+		s := slices.Clone(spec)
+		s[0].Urls = slices.Clone(s[0].Urls)
+		broken(s)
+		if validateSpec(s) == nil {
+			t.Errorf("%+v should fail validation", s)
+		}
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"results":[
+{"header":{"index_id":45,"index_name":"Index #45: New Site - a.jpg"},"data":{"ext_urls":["https://new.example/1"],"post_id":1,"score":1,"rating":null}},
+{"header":{"index_id":45,"index_name":"Index #45: New Site - b.jpg"},"data":{"post_id":2,"score":2.5,"tags":["a",1]}},
+{"header":{"index_id":5,"index_name":"Index #5: Pixiv Images - c.jpg"},"data":{"pixiv_id":3}}]}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "b.jsonl"), []byte(
+		`{"method":"GET","path":"/search.php","status_code":200,"body":"{\"results\":[{\"header\":{\"index_id\":45},\"data\":{\"post_id\":3}}]}"}`+"\n"), 0o644)
+
+	spec := []Index{{Id: 5, Const: "PIXIV", Name: "pixiv Images", Type: "Pixiv"}}
+	out, err := bootstrap(spec, []string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 || out[0].Const != "PIXIV" || out[1].Const != "NEWSITE" || out[1].Type != "NewSite" {
+		t.Fatalf("out = %+v", out)
+	}
+	want := []Field{
+		{Name: "ExtUrls", Json: "ext_urls", Type: "[]string"},
+		{Name: "PostId", Json: "post_id", Type: "int"},
+		{Name: "Rating", Json: "rating", Type: "any"},
+		{Name: "Score", Json: "score", Type: "float64"},
+		{Name: "Tags", Json: "tags", Type: "any"},
+	}
+	if !slices.Equal(out[1].Fields, want) {
+		t.Errorf("fields = %+v", out[1].Fields)
+	}
+	if err := validateSpec(out); err != nil {
+		t.Errorf("bootstrapped spec is invalid: %v", err)
+	}
+}
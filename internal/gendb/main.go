@@ -0,0 +1,74 @@
+// gendb 根据 db/indexes.json 生成索引常量, 名称表, 类型表, 结构体与解码分派, 由 db/db.go 中的 go:generate 调用
+//
+//	go generate ./db
+//
+// 新增索引时先用抓到的响应生成字段列表, 调整后加入 indexes.json 再重新生成.
+// String() 的格式与 CanonicalURLs() 的链接在 string, urls 中给出, 见 [Index]
+//
+//	go run ./internal/gendb -spec db/indexes.json -sample testdata/cassette.jsonl
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	specPath := flag.String("spec", "indexes.json", "index spec")
+	indexesOut := flag.String("indexes", "indexes_gen.go", "output for constants, name and type tables")
+	structsOut := flag.String("structs", "structs_gen.go", "output for generated structs")
+	decodeOut := flag.String("decode", "../decode_gen.go", "output for the decode dispatch in package SauceNao")
+	sample := flag.Bool("sample", false, "print spec entries with fields inferred from the responses given as arguments, instead of generating")
+	flag.Parse()
+
+	err := run(*specPath, *indexesOut, *structsOut, *decodeOut, *sample, flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gendb:", err)
+		os.Exit(1)
+	}
+}
+
+func run(specPath, indexesOut, structsOut, decodeOut string, sample bool, args []string) error {
+	spec, err := loadSpec(specPath)
+	if err != nil {
+		return err
+	}
+	if sample {
+		if len(args) == 0 {
+			return fmt.Errorf("-sample needs response files or directories")
+		}
+		entries, err := bootstrap(spec, args)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	for _, out := range []struct {
+		path string
+		gen  func([]Index) ([]byte, error)
+	}{
+		{indexesOut, genIndexes},
+		{structsOut, genStructs},
+		{decodeOut, genDecode},
+	} {
+		src, err := out.gen(spec)
+		if err != nil {
+			return err
+		}
+		// 内容不变时不写入, 保留修改时间
+		if old, err := os.ReadFile(out.path); err == nil && bytes.Equal(old, src) {
+			continue
+		}
+		err = os.WriteFile(out.path, src, 0o644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
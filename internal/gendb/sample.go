@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// 不依赖 SauceNao 与 cassette 包, 以便生成的代码无法编译时仍能运行
+
+// response api 响应中用到的部分
+type response struct {
+	Results []struct {
+		Header struct {
+			IndexId   int    `json:"index_id"`
+			IndexName string `json:"index_name"`
+		} `json:"header"`
+		Data map[string]any `json:"data"`
+	} `json:"results"`
+}
+
+// interaction cassette 中的一行, 见 [cassette.Interaction]
+type interaction struct {
+	Path       string `json:"path"`
+	StatusCode int    `json:"status_code"`
+	Body       string `json:"body"`
+}
+
+const API_PATH = "/search.php"
+
+// sampleIndex 从样本中收集到的单个索引
+type sampleIndex struct {
+	id    int
+	name  string
+	types map[string]string // json 键 -> 推断的类型
+}
+
+// bootstrap 读取样本 (api 响应 *.json 或 cassette *.jsonl), 为出现过的索引给出带 fields 的规格.
+// 规格中已有的索引沿用其 const, name 与 type, 已有的字段保留原名与注释
+func bootstrap(spec []Index, paths []string) ([]Index, error) {
+	samples := map[int]*sampleIndex{}
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			if ext := filepath.Ext(path); path != root && ext != ".json" && ext != ".jsonl" {
+				return nil
+			}
+			bodies, err := readBodies(path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			for _, body := range bodies {
+				var resp response
+				if json.Unmarshal(body, &resp) != nil {
+					continue
+				}
+				for _, r := range resp.Results {
+					addSample(samples, r.Header.IndexId, r.Header.IndexName, r.Data)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var out []Index
+	for _, id := range slices.Sorted(maps.Keys(samples)) {
+		s := samples[id]
+		idx := Index{Id: id}
+		if i := slices.IndexFunc(spec, func(idx Index) bool { return idx.Id == id }); i >= 0 {
+			idx = spec[i]
+			idx.Todo = false
+		} else {
+			idx.Name = s.name
+			idx.Type = goName(strings.ToLower(s.name))
+			idx.Const = strings.ToUpper(reNonWord.ReplaceAllString(s.name, ""))
+		}
+		fields := idx.Fields
+		idx.Fields = nil
+		for _, key := range slices.Sorted(maps.Keys(s.types)) {
+			f := Field{Name: goName(key), Json: key, Type: cmp.Or(s.types[key], "any")}
+			if i := slices.IndexFunc(fields, func(f Field) bool { return f.Json == key }); i >= 0 {
+				f.Name, f.Comment = fields[i].Name, fields[i].Comment
+			}
+			idx.Fields = append(idx.Fields, f)
+		}
+		out = append(out, idx)
+	}
+	return out, nil
+}
+
+// readBodies *.jsonl 按 cassette 读取成功的 search.php 响应, 其他文件整体作为一个响应
+func readBodies(path string) ([][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".jsonl") {
+		return [][]byte{data}, nil
+	}
+	var bodies [][]byte
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 64<<20)
+	for sc.Scan() {
+		var in interaction
+		if json.Unmarshal(sc.Bytes(), &in) == nil && in.Path == API_PATH && in.StatusCode == http.StatusOK {
+			bodies = append(bodies, []byte(in.Body))
+		}
+	}
+	return bodies, sc.Err()
+}
+
+// reIndexName "Index #41: Twitter - 1234567890.jpg"
+var reIndexName = regexp.MustCompile(`^Index #\d+: (.+?) - `)
+
+var reNonWord = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+func addSample(samples map[int]*sampleIndex, id int, indexName string, data map[string]any) {
+	s := samples[id]
+	if s == nil {
+		s = &sampleIndex{id: id, name: fmt.Sprintf("Index %d", id), types: map[string]string{}}
+		samples[id] = s
+	}
+	if m := reIndexName.FindStringSubmatch(indexName); m != nil {
+		s.name = m[1]
+	}
+	for k, v := range data {
+		t := inferType(v)
+		if prev, ok := s.types[k]; ok {
+			t = mergeTypes(prev, t)
+		}
+		s.types[k] = t
+	}
+}
+
+// inferType json 值对应的字段类型, null 为 ""
+func inferType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return "int"
+		}
+		return "float64"
+	case []any:
+		for _, e := range v {
+			if _, ok := e.(string); !ok {
+				return "any"
+			}
+		}
+		return "[]string"
+	}
+	return "any"
+}
+
+func mergeTypes(a, b string) string {
+	switch {
+	case a == b || b == "":
+		return a
+	case a == "":
+		return b
+	case a == "int" && b == "float64", a == "float64" && b == "int":
+		return "float64"
+	}
+	return "any"
+}
+
+// goName "ext_urls" -> "ExtUrls", 与仓库中 Id, Url 的写法一致
+func goName(key string) string {
+	var b strings.Builder
+	for part := range strings.FieldsFuncSeq(key, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	name := b.String()
+	if name == "" || '0' <= name[0] && name[0] <= '9' {
+		name = "F" + name
+	}
+	return name
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Index 规格文件中的一个索引
+//
+// 三种情况:
+//   - todo: 生成只有 Todo 字段的占位结构体
+//   - fields 非空: 生成结构体与 String, Json, CanonicalURLs 方法
+//   - 只有 struct: 与其他索引共用结构体, 生成类型别名
+type Index struct {
+	Id     int     `json:"id"`
+	Const  string  `json:"const"`            // db 中的常量名, 如 "PIXIV"
+	Name   string  `json:"name"`             // 如 "pixiv Images"
+	Type   string  `json:"type"`             // 结构体名去掉 ResultData 前缀, 如 "Pixiv"
+	Struct string  `json:"struct,omitempty"` // 多个索引共用的结构体名, Type 为其别名, 由其中一个索引给出 fields
+	Todo   bool    `json:"todo,omitempty"`
+	Fields []Field `json:"fields,omitempty"`
+	String string  `json:"string,omitempty"` // String() 的模板, 见 [parseTemplate], 为空时每个字段一行
+	Urls   []Url   `json:"urls,omitempty"`
+}
+
+// Field 结构体字段, 名为 ext_urls 的 []string 会加入 CanonicalURLs
+type Field struct {
+	Name    string `json:"name"` // Go 字段名
+	Json    string `json:"json"`
+	Type    string `json:"type"` // string | int | float64 | bool | []string | any
+	Comment string `json:"comment,omitempty"`
+}
+
+// Url CanonicalURLs 中的一条链接, format, raw, source 三选一
+type Url struct {
+	Site   string   `json:"site,omitempty"`   // db 中的 Site 常量名, 如 "SITE_PIXIV"
+	Format string   `json:"format,omitempty"` // 动词依次取 fields 对应的字段, 任一为零值时跳过
+	Fields []string `json:"fields,omitempty"`
+	Raw    string   `json:"raw,omitempty"`    // 值本身是完整链接的字段
+	Source string   `json:"source,omitempty"` // 需识别站点的 source 字段
+	If     string   `json:"if,omitempty"`     // "Field" 非零值, "!Field" 零值, 或 `Field == "value"`
+}
+
+var (
+	reConst = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+	reIdent = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+	reSite  = regexp.MustCompile(`^SITE_[A-Z0-9_]+$`)
+	reIfEq  = regexp.MustCompile(`^([A-Z][A-Za-z0-9]*) == (".*")$`)
+)
+
+var fieldTypes = []string{"string", "int", "float64", "bool", "[]string", "any"}
+
+func loadSpec(path string) ([]Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec []Index
+	err = json.Unmarshal(data, &spec)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return spec, validateSpec(spec)
+}
+
+func validateSpec(spec []Index) error {
+	ids := map[int]bool{}
+	consts := map[string]bool{}
+	types := map[string]bool{}
+	structs := map[string]int{} // 共用结构体 -> 给出 fields 的索引数
+	for _, idx := range spec {
+		switch {
+		case idx.Id < 0 || idx.Id >= 64:
+			return fmt.Errorf("index %d: id must be in [0, 64)", idx.Id)
+		case ids[idx.Id]:
+			return fmt.Errorf("index %d: duplicate id", idx.Id)
+		case !reConst.MatchString(idx.Const) || consts[idx.Const]:
+			return fmt.Errorf("index %d: invalid or duplicate const %q", idx.Id, idx.Const)
+		case !reIdent.MatchString(idx.Type) || types[idx.Type]:
+			return fmt.Errorf("index %d: invalid or duplicate type %q", idx.Id, idx.Type)
+		case idx.Struct != "" && (!reIdent.MatchString(idx.Struct) || idx.Struct == idx.Type):
+			return fmt.Errorf("index %d: invalid struct %q", idx.Id, idx.Struct)
+		case idx.Todo && (len(idx.Fields) > 0 || idx.Struct != ""):
+			return fmt.Errorf("index %d: todo index cannot have fields or struct", idx.Id)
+		case !idx.Todo && len(idx.Fields) == 0 && idx.Struct == "":
+			return fmt.Errorf("index %d: needs fields, struct or todo", idx.Id)
+		case (len(idx.Urls) > 0 || idx.String != "") && len(idx.Fields) == 0:
+			return fmt.Errorf("index %d: urls and string need fields", idx.Id)
+		}
+		ids[idx.Id], consts[idx.Const], types[idx.Type] = true, true, true
+		if idx.Struct != "" {
+			if _, ok := structs[idx.Struct]; !ok {
+				structs[idx.Struct] = 0
+			}
+			if len(idx.Fields) > 0 {
+				structs[idx.Struct]++
+			}
+		}
+
+		names := map[string]bool{}
+		for _, f := range idx.Fields {
+			if !reIdent.MatchString(f.Name) || names[f.Name] || f.Json == "" {
+				return fmt.Errorf("index %d: invalid or duplicate field %q", idx.Id, f.Name)
+			}
+			if !slices.Contains(fieldTypes, f.Type) {
+				return fmt.Errorf("index %d: field %s: unsupported type %q", idx.Id, f.Name, f.Type)
+			}
+			names[f.Name] = true
+		}
+		if idx.String != "" {
+			_, _, err := idx.parseTemplate()
+			if err != nil {
+				return fmt.Errorf("index %d: string: %w", idx.Id, err)
+			}
+		}
+		for _, u := range idx.Urls {
+			err := idx.validateUrl(u)
+			if err != nil {
+				return fmt.Errorf("index %d: url %+v: %w", idx.Id, u, err)
+			}
+		}
+	}
+	for name, n := range structs {
+		if n != 1 || types[name] {
+			return fmt.Errorf("struct %s must be defined by exactly one index and not clash with a type", name)
+		}
+	}
+	return nil
+}
+
+func (idx *Index) validateUrl(u Url) error {
+	set := 0
+	for _, v := range []string{u.Format, u.Raw, u.Source} {
+		if v != "" {
+			set++
+		}
+	}
+	switch {
+	case set != 1:
+		return fmt.Errorf("needs exactly one of format, raw and source")
+	case u.Source != "" && u.Site != "":
+		return fmt.Errorf("source urls take the site from the value")
+	case u.Source == "" && !reSite.MatchString(u.Site):
+		return fmt.Errorf("invalid site %q", u.Site)
+	case u.Format == "" && len(u.Fields) > 0:
+		return fmt.Errorf("fields need format")
+	}
+	for _, name := range append(slices.Clone(u.Fields), u.Raw, u.Source) {
+		if name != "" && idx.fieldByName(name) == nil {
+			return fmt.Errorf("unknown field %s", name)
+		}
+	}
+	if u.If != "" {
+		_, err := idx.condition(u.If)
+		return err
+	}
+	return nil
+}
+
+// generated 结构体由生成器产生
+func (idx *Index) generated() bool {
+	return idx.Todo || len(idx.Fields) > 0 || idx.Struct != ""
+}
+
+// structName 结构体名去掉 ResultData 前缀
+func (idx *Index) structName() string {
+	if idx.Struct != "" {
+		return idx.Struct
+	}
+	return idx.Type
+}
+
+func (idx *Index) field(json string) *Field {
+	for i := range idx.Fields {
+		if idx.Fields[i].Json == json {
+			return &idx.Fields[i]
+		}
+	}
+	return nil
+}
+
+func (idx *Index) fieldByName(name string) *Field {
+	for i := range idx.Fields {
+		if idx.Fields[i].Name == name {
+			return &idx.Fields[i]
+		}
+	}
+	return nil
+}
+
+// condition 将 Url.If 转为 Go 表达式
+func (idx *Index) condition(cond string) (string, error) {
+	if m := reIfEq.FindStringSubmatch(cond); m != nil {
+		f := idx.fieldByName(m[1])
+		if _, err := strconv.Unquote(m[2]); err != nil || f == nil || f.Type != "string" {
+			return "", fmt.Errorf("invalid condition %q", cond)
+		}
+		return "rd." + m[1] + " == " + m[2], nil
+	}
+	name, negate := strings.CutPrefix(cond, "!")
+	f := idx.fieldByName(name)
+	if f == nil {
+		return "", fmt.Errorf("condition %q refers to unknown field", cond)
+	}
+	var zero string
+	switch f.Type {
+	case "string":
+		zero = `""`
+	case "int", "float64":
+		zero = "0"
+	default:
+		return "", fmt.Errorf("condition %q needs a string or number field", cond)
+	}
+	if negate {
+		return "rd." + name + " == " + zero, nil
+	}
+	return "rd." + name + " != " + zero, nil
+}
+
+// templateFuncs String 模板中可用的函数, 值为 Go 中的函数名, 结果均为 string
+var templateFuncs = map[string]string{
+	"or":         "cmp.Or",     // 第一个非空的字段
+	"formatTime": "formatTime", // 见 db/format.go
+	"optional":   "optional",   // 见 db/format.go
+}
+
+// parseTemplate 将 String 模板转为 fmt 格式与参数.
+// {Field} 按字段类型格式化 ([]string 以 ", " 连接, ext_urls 以换行连接);
+// {func Field "literal" ...} 调用 [templateFuncs] 中的函数
+func (idx *Index) parseTemplate() (format string, args []string, err error) {
+	var b strings.Builder
+	rest := idx.String
+	for {
+		i := strings.IndexByte(rest, '{')
+		if i < 0 {
+			b.WriteString(strings.ReplaceAll(rest, "%", "%%"))
+			break
+		}
+		b.WriteString(strings.ReplaceAll(rest[:i], "%", "%%"))
+		j := strings.IndexByte(rest[i:], '}')
+		if j < 0 {
+			return "", nil, fmt.Errorf("unclosed {")
+		}
+		verb, arg, err := idx.placeholder(rest[i+1 : i+j])
+		if err != nil {
+			return "", nil, err
+		}
+		b.WriteString(verb)
+		args = append(args, arg)
+		rest = rest[i+j+1:]
+	}
+	if strings.Contains(b.String(), "`") {
+		return "", nil, fmt.Errorf("template cannot contain `")
+	}
+	return b.String(), args, nil
+}
+
+func (idx *Index) placeholder(s string) (verb, arg string, err error) {
+	var tokens []string
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		if s[0] == '"' {
+			q, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return "", "", fmt.Errorf("invalid literal in %q", s)
+			}
+			tokens = append(tokens, q)
+			s = s[len(q):]
+			continue
+		}
+		tok, rest, _ := strings.Cut(s, " ")
+		tokens = append(tokens, tok)
+		s = rest
+	}
+	if len(tokens) == 0 {
+		return "", "", fmt.Errorf("empty placeholder")
+	}
+
+	if len(tokens) == 1 {
+		f := idx.fieldByName(tokens[0])
+		if f == nil {
+			return "", "", fmt.Errorf("unknown field %s", tokens[0])
+		}
+		verb, arg := fieldVerb(f)
+		return verb, arg, nil
+	}
+
+	fn, ok := templateFuncs[tokens[0]]
+	if !ok {
+		return "", "", fmt.Errorf("unknown function %s", tokens[0])
+	}
+	var fnArgs []string
+	for _, tok := range tokens[1:] {
+		if tok[0] == '"' {
+			fnArgs = append(fnArgs, tok)
+			continue
+		}
+		if idx.fieldByName(tok) == nil {
+			return "", "", fmt.Errorf("unknown field %s", tok)
+		}
+		fnArgs = append(fnArgs, "rd."+tok)
+	}
+	return "%s", fn + "(" + strings.Join(fnArgs, ", ") + ")", nil
+}
+
+// fieldVerb 字段在 String 中的格式与参数
+func fieldVerb(f *Field) (verb, arg string) {
+	arg = "rd." + f.Name
+	switch f.Type {
+	case "string":
+		return "%s", arg
+	case "int":
+		return "%d", arg
+	case "float64":
+		return "%g", arg
+	case "bool":
+		return "%t", arg
+	case "[]string":
+		sep := ", "
+		if f.Json == "ext_urls" {
+			sep = "\n"
+		}
+		return "%s", fmt.Sprintf("strings.Join(%s, %q)", arg, sep)
+	}
+	return "%v", arg
+}
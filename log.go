@@ -0,0 +1,53 @@
+package SauceNao
+
+import (
+	"log/slog"
+	"net/url"
+)
+
+// 日志中统一使用的属性名
+const (
+	LOG_METHOD   = "method"
+	LOG_URL      = "url"
+	LOG_STATUS   = "status"
+	LOG_DURATION = "duration"
+	LOG_UPSTREAM = "upstream" // [UPSTREAM_SAUCENAO] | [UPSTREAM_FLARESOLVERR]
+	LOG_ATTEMPT  = "attempt"  // 从 1 开始
+	LOG_INDEX_ID = "index_id"
+	LOG_ERROR    = "error"
+
+	LOG_SHORT_REMAINING = "short_remaining"
+	LOG_LONG_REMAINING  = "long_remaining"
+)
+
+const REDACTED = "REDACTED"
+
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// logger 未设置 [Client.Logger] 时丢弃
+func (c *Client) logger() *slog.Logger {
+	if c == nil || c.Logger == nil {
+		return discardLogger
+	}
+	return c.Logger
+}
+
+// redactUrl 隐去 api_key, 用于日志与 [HttpError]
+func redactUrl(u *url.URL) string {
+	q := u.Query()
+	if !q.Has("api_key") {
+		return u.String()
+	}
+	q.Set("api_key", REDACTED)
+	r := *u
+	r.RawQuery = q.Encode()
+	return r.String()
+}
+
+func redactUrlString(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	return redactUrl(u)
+}
@@ -0,0 +1,41 @@
+package SauceNao
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogRedactsApiKey(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	c := NewClient("secret-key", srv.URL, 0, HIDE_NONE, nil)
+	c.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	_, err := c.Get(t.Context(), "https://example.com/a.png")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if strings.Contains(err.Error(), "secret-key") || strings.Contains(buf.String(), "secret-key") {
+		t.Errorf("api key leaked:\n%v\n%s", err, buf.String())
+	}
+	for _, want := range []string{"request start", "request error", "status=500", "api_key=" + REDACTED} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, buf.String())
+		}
+	}
+
+	// 解码失败经由结果回到 client 的 logger
+	buf.Reset()
+	r := Result{Header: ResultHeader{IndexId: 5}, Data: map[string]any{"pixiv_id": []int{1}}, client: c}
+	r.DecodeData()
+	if !strings.Contains(buf.String(), "decode failed") {
+		t.Errorf("decode failure not logged:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,150 @@
+package SauceNao
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"reflect"
+	"strings"
+
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+// KIND_UNKNOWN 索引没有完成的结构体, 或解码失败, 此时 data 为原始数据
+const KIND_UNKNOWN = "unknown"
+
+// storedResult [Result] 序列化后的格式, 读取时 Kind 为空即为 api 的原始格式
+//
+//	{"header": {...}, "kind": "Pixiv", "index_id": 5, "data": {...}, "extra": {...}, "missing": [...]}
+type storedResult struct {
+	Header  ResultHeader    `json:"header"`
+	Kind    string          `json:"kind,omitempty"` // 结构体名去掉 ResultData 前缀, 或 [KIND_UNKNOWN]
+	IndexId *db.IndexId     `json:"index_id,omitempty"`
+	Data    json.RawMessage `json:"data"`
+	Extra   map[string]any  `json:"extra,omitempty"`   // 原始 data 中结构体没有的字段
+	Missing []string        `json:"missing,omitempty"` // 结构体中有但原始 data 没有的字段
+	Error   string          `json:"error,omitempty"`   // 解码失败时的错误
+}
+
+// MarshalJSON 写入解码后的结构体与 kind, index_id, 再读取时不需要重新解码.
+// 结构体没有的字段存入 extra, 原始 data 中没有的字段列于 missing, 以便还原 [Result.Data]
+func (r Result) MarshalJSON() ([]byte, error) {
+	id := r.Header.IndexId
+	s := storedResult{Header: r.Header, Kind: KIND_UNKNOWN, IndexId: &id}
+	rd := r.DecodeData()
+	unknown, isUnknown := rd.(*db.ResultDataUnknown)
+	_, typed := id.Fields()
+
+	var err error
+	switch {
+	case isUnknown || !typed:
+		if isUnknown && unknown.Err != nil {
+			s.Error = unknown.Err.Error()
+		}
+		s.Data = r.rawData
+		if len(s.Data) == 0 {
+			s.Data, err = json.Marshal(r.Data)
+		}
+	default:
+		s.Kind = kindOf(rd)
+		s.Data, err = json.Marshal(rd)
+		drift := db.CheckSchema(id, r.Data)
+		s.Missing = drift.Missing
+		for _, k := range drift.Unknown {
+			if s.Extra == nil {
+				s.Extra = map[string]any{}
+			}
+			s.Extra[k] = r.Data[k]
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s)
+}
+
+// UnmarshalJSON 同时接受 api 的原始格式与 [Result.MarshalJSON] 的格式.
+// 后者的 kind 与当前索引的结构体一致时直接得到结构体, 否则按原始数据处理
+func (r *Result) UnmarshalJSON(data []byte) error {
+	var s storedResult
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	*r = Result{Header: s.Header}
+	if s.IndexId != nil && *s.IndexId != s.Header.IndexId {
+		return errors.New("result index_id does not match header")
+	}
+	if len(s.Data) > 0 {
+		err = json.Unmarshal(s.Data, &r.Data)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case s.Kind == "":
+		r.rawData = s.Data
+		return nil
+	case s.Kind == KIND_UNKNOWN:
+		r.rawData = s.Data
+		if s.Error != "" {
+			r.typed = &db.ResultDataUnknown{Raw: r.Data, Err: errors.New(s.Error)}
+		}
+		return nil
+	}
+
+	if r.Data == nil {
+		r.Data = map[string]any{}
+	}
+	for _, k := range s.Missing {
+		delete(r.Data, k)
+	}
+	maps.Copy(r.Data, s.Extra)
+	if s.Kind != kindOfIndex(r.Header.IndexId) {
+		// 结构体已改变, 之后按原始数据解码
+		return nil
+	}
+	rd, err := decodeIndex(r.Header.IndexId, nil)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(s.Data, rd)
+	if err != nil {
+		return err
+	}
+	r.typed = rd
+	return nil
+}
+
+// kindOf *db.ResultDataPixiv -> "Pixiv"
+func kindOf(rd db.ResultData) string {
+	t := reflect.TypeOf(rd)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return strings.TrimPrefix(t.Name(), "ResultData")
+}
+
+// kindOfIndex 索引当前对应的 kind, 没有完成的结构体时为 [KIND_UNKNOWN]
+func kindOfIndex(id db.IndexId) string {
+	if _, ok := id.Fields(); !ok {
+		return KIND_UNKNOWN
+	}
+	rd, err := decodeIndex(id, nil)
+	if err != nil || rd == nil {
+		return KIND_UNKNOWN
+	}
+	return kindOf(rd)
+}
+
+// copyResultData 浅拷贝, 避免调用方修改缓存的结构体
+func copyResultData(rd db.ResultData) db.ResultData {
+	v := reflect.ValueOf(rd)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return rd
+	}
+	c := reflect.New(v.Elem().Type())
+	c.Elem().Set(v.Elem())
+	return c.Interface().(db.ResultData)
+}
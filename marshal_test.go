@@ -0,0 +1,154 @@
+package SauceNao
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+const marshalSample = `{"header":{"status":0,"short_remaining":3},"results":[
+{"header":{"similarity":"95.00","index_id":5},"data":{"ext_urls":["https://www.pixiv.net/artworks/1"],"title":"t","pixiv_id":9007199254740993,"member_id":2,"like_count":7}},
+{"header":{"similarity":"90.00","index_id":11},"data":{"nijie_id":3}},
+{"header":{"similarity":"85.00","index_id":5},"data":{"pixiv_id":"abc"}},
+{"header":{"similarity":"80.00","index_id":63},"data":{"foo":"bar"}}]}`
+
+func TestResultJsonRoundTrip(t *testing.T) {
+	var orig Response
+	if err := json.Unmarshal([]byte(marshalSample), &orig); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := json.Marshal(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(stored), `"kind":"Pixiv","index_id":5`) || !strings.Contains(string(stored), `"extra":{"like_count":7}`) {
+		t.Errorf("stored = %s", stored)
+	}
+
+	var loaded Response
+	if err := json.Unmarshal(stored, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Results) != len(orig.Results) {
+		t.Fatalf("loaded %d results", len(loaded.Results))
+	}
+	for i, r := range loaded.Results {
+		want := orig.Results[i]
+		if !reflect.DeepEqual(r.Header, want.Header) || !reflect.DeepEqual(r.Data, want.Data) {
+			t.Errorf("%d: loaded %+v, want %+v", i, r, want)
+		}
+		got, wantData := r.DecodeData(), want.DecodeData()
+		if u, ok := wantData.(*db.ResultDataUnknown); ok && u.Err != nil {
+			// 错误只保留文本
+			if gu, ok := got.(*db.ResultDataUnknown); !ok || gu.Err == nil || gu.Err.Error() != u.Err.Error() {
+				t.Errorf("%d: decode error %#v", i, got)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got, wantData) {
+			t.Errorf("%d: typed %#v, want %#v", i, got, wantData)
+		}
+	}
+	if loaded.Results[0].typed == nil {
+		t.Error("typed data should be restored without re-decoding")
+	}
+	if id := loaded.Results[0].DecodeData().(*db.ResultDataPixiv).PixivId; id != 9007199254740993 {
+		t.Errorf("pixiv id = %d", id)
+	}
+
+	// 修改返回的结构体不影响之后的结果
+	loaded.Results[0].DecodeData().(*db.ResultDataPixiv).Title = "changed"
+	if loaded.Results[0].DecodeData().(*db.ResultDataPixiv).Title != "t" {
+		t.Error("DecodeData returned the cached struct")
+	}
+}
+
+func TestResultJsonKindMismatch(t *testing.T) {
+	var r Result
+	err := json.Unmarshal([]byte(`{"header":{"index_id":5},"kind":"OldPixiv","index_id":5,"data":{"pixiv_id":1,"title":""},"missing":["title"],"extra":{"x":1}}`), &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.typed != nil || r.Data["title"] != nil || r.Data["x"] != 1.0 {
+		t.Errorf("result = %+v", r)
+	}
+	if rd, ok := r.DecodeData().(*db.ResultDataPixiv); !ok || rd.PixivId != 1 {
+		t.Errorf("decoded %#v", r.DecodeData())
+	}
+
+	err = json.Unmarshal([]byte(`{"header":{"index_id":5},"kind":"Pixiv","index_id":9,"data":{}}`), &r)
+	if err == nil {
+		t.Error("mismatched index_id should fail")
+	}
+}
+
+// 随机结构体 -> Result -> 存储格式 -> Result 得到相同的结构体与原始数据
+func TestResultJsonProperty(t *testing.T) {
+	for id := db.IndexId(0); id < 64; id++ {
+		if _, ok := id.Fields(); !ok {
+			continue
+		}
+		for i := range 20 {
+			rd, _ := decodeIndex(id, nil)
+			fillIndex(reflect.ValueOf(rd).Elem(), i)
+			raw, _ := json.Marshal(rd)
+			var orig Result
+			err := json.Unmarshal(fmt.Appendf(nil, `{"header":{"index_id":%d,"similarity":"50.00"},"data":%s}`, id, raw), &orig)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stored, err := json.Marshal(orig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var loaded Result
+			if err := json.Unmarshal(stored, &loaded); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(loaded.Data, orig.Data) || !reflect.DeepEqual(loaded.DecodeData(), orig.DecodeData()) {
+				t.Fatalf("%d %s: %s", id, id, stored)
+			}
+		}
+	}
+}
+
+// fillIndex 按字段序号与 seed 填入可区分的值
+func fillIndex(v reflect.Value, seed int) {
+	for i := range v.NumField() {
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.String:
+			f.SetString(fmt.Sprintf("s%d-%d \"\\ é", seed, i))
+		case reflect.Int:
+			f.SetInt(int64(seed*1000+i) << 40)
+		case reflect.Slice:
+			if seed%2 == 1 {
+				f.Set(reflect.ValueOf([]string{fmt.Sprint(seed), "x"}))
+			}
+		}
+	}
+}
+
+func TestKeepRawBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"header":{"status":0},"results":[]}`)
+	}))
+	defer srv.Close()
+	c := NewClient("key", srv.URL, 0, HIDE_NONE, nil)
+
+	resp, err := c.Post(t.Context(), []byte("a"))
+	if err != nil || resp.RawBody != "" {
+		t.Errorf("default raw body = %q, %v", resp.RawBody, err)
+	}
+	resp, err = c.Post(t.Context(), []byte("a"), WithRawBody(true))
+	if err != nil || !strings.Contains(resp.RawBody, `"results":[]`) {
+		t.Errorf("WithRawBody raw body = %q, %v", resp.RawBody, err)
+	}
+}
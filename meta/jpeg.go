@@ -0,0 +1,159 @@
+package meta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// JPEG_COMMENT_HEADER COM 段的首行, 其后每行一个 "key=value"
+const JPEG_COMMENT_HEADER = "SauceNAO"
+
+const (
+	marker_soi   = 0xD8
+	marker_sos   = 0xDA
+	marker_com   = 0xFE
+	marker_app0  = 0xE0
+	marker_app1  = 0xE1
+	marker_app15 = 0xEF
+)
+
+var xmpSignature = []byte("http://ns.adobe.com/xap/1.0/\x00")
+
+var errJpegMalformed = errors.New("malformed jpeg")
+
+type jpegSegment struct {
+	marker byte
+	data   []byte // 不含长度
+}
+
+// splitJpeg 拆分 SOS 之前的段, rest 为 SOS 段起 (含) 的剩余部分, 原样保留
+func splitJpeg(data []byte) (segs []jpegSegment, rest []byte, err error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != marker_soi {
+		return nil, nil, errJpegMalformed
+	}
+	i := 2
+	for {
+		// 标记前可能有填充的 0xFF
+		for i+1 < len(data) && data[i] == 0xFF && data[i+1] == 0xFF {
+			i++
+		}
+		if i+4 > len(data) || data[i] != 0xFF {
+			return nil, nil, errJpegMalformed
+		}
+		m := data[i+1]
+		if m == marker_sos {
+			return segs, data[i:], nil
+		}
+		n := int(binary.BigEndian.Uint16(data[i+2:]))
+		if n < 2 || i+2+n > len(data) {
+			return nil, nil, errJpegMalformed
+		}
+		segs = append(segs, jpegSegment{m, data[i+4 : i+2+n]})
+		i += 2 + n
+	}
+}
+
+func appendJpegSegment(b []byte, s jpegSegment) ([]byte, error) {
+	if len(s.data) > 0xFFFF-2 {
+		return nil, fmt.Errorf("jpeg segment too large: %d bytes", len(s.data))
+	}
+	b = append(b, 0xFF, s.marker)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s.data)+2))
+	return append(b, s.data...), nil
+}
+
+func isXmpSegment(s jpegSegment) bool {
+	return s.marker == marker_app1 && bytes.HasPrefix(s.data, xmpSignature)
+}
+
+func isOurComment(s jpegSegment) bool {
+	return s.marker == marker_com && bytes.HasPrefix(s.data, []byte(JPEG_COMMENT_HEADER+"\n"))
+}
+
+func marshalComment(info Info) []byte {
+	var b strings.Builder
+	b.WriteString(JPEG_COMMENT_HEADER)
+	for _, k := range keys {
+		// 值中的换行会破坏格式, 替换为空格
+		v := strings.NewReplacer("\r", " ", "\n", " ").Replace(info.get(k))
+		b.WriteString("\n" + k + "=" + v)
+	}
+	return []byte(b.String())
+}
+
+func parseComment(data []byte) *Info {
+	info := &Info{}
+	lines := strings.Split(string(data), "\n")
+	for _, line := range lines[1:] {
+		if k, v, ok := strings.Cut(line, "="); ok {
+			info.set(k, v)
+		}
+	}
+	return info
+}
+
+// EmbedJpeg 在 APPn 段之后写入 COM 段与 APP1 XMP 段, 并移除此前写入的.
+// 文件中已有其他来源的 XMP 时保留它, 只写 COM 段
+func EmbedJpeg(data []byte, info Info) ([]byte, error) {
+	segs, rest, err := splitJpeg(data)
+	if err != nil {
+		return nil, err
+	}
+	foreignXmp := false
+	kept := segs[:0:0]
+	for _, s := range segs {
+		if isOurComment(s) || (isXmpSegment(s) && isOurXmp(s.data)) {
+			continue
+		}
+		if isXmpSegment(s) {
+			foreignXmp = true
+		}
+		kept = append(kept, s)
+	}
+
+	ours := []jpegSegment{{marker_com, marshalComment(info)}}
+	if !foreignXmp {
+		ours = append(ours, jpegSegment{marker_app1, append(bytes.Clone(xmpSignature), MarshalXmp(info)...)})
+	}
+
+	// 插入在开头的 APPn (JFIF, Exif 等) 之后
+	at := 0
+	for at < len(kept) && kept[at].marker >= marker_app0 && kept[at].marker <= marker_app15 {
+		at++
+	}
+	kept = append(kept[:at], append(ours, kept[at:]...)...)
+
+	out := make([]byte, 0, len(data)+1024)
+	out = append(out, 0xFF, marker_soi)
+	for _, s := range kept {
+		out, err = appendJpegSegment(out, s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append(out, rest...), nil
+}
+
+// ReadJpeg 读取 [EmbedJpeg] 写入的信息, XMP 优先, 没有时返回 [ErrNotTagged]
+func ReadJpeg(data []byte) (*Info, error) {
+	segs, _, err := splitJpeg(data)
+	if err != nil {
+		return nil, err
+	}
+	var comment *Info
+	for _, s := range segs {
+		switch {
+		case isXmpSegment(s) && isOurXmp(s.data):
+			return ParseXmp(s.data[len(xmpSignature):])
+		case isOurComment(s):
+			comment = parseComment(s.data)
+		}
+	}
+	if comment == nil {
+		return nil, ErrNotTagged
+	}
+	return comment, nil
+}
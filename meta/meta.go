@@ -0,0 +1,232 @@
+// Package meta 将选定的搜索结果写入图片文件本身, 不重新编码像素:
+// PNG 写 tEXt/iTXt 块, JPEG 写 COM 与 APP1 XMP 段, 其他格式写 .xmp sidecar
+package meta
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+const SIDECAR_EXT = ".xmp"
+
+var ErrNotTagged = errors.New("no saucenao metadata")
+
+// Info 写入文件的来源信息
+type Info struct {
+	Url        string     `json:"url"`
+	Artist     string     `json:"artist"`
+	Title      string     `json:"title"`
+	IndexId    db.IndexId `json:"index_id"`
+	IndexName  string     `json:"index_name"`
+	Similarity float64    `json:"similarity"`
+}
+
+// 各格式共用的字段名, 顺序即写入顺序
+const (
+	KEY_URL        = "url"
+	KEY_ARTIST     = "artist"
+	KEY_TITLE      = "title"
+	KEY_INDEX_ID   = "index_id"
+	KEY_INDEX_NAME = "index_name"
+	KEY_SIMILARITY = "similarity"
+)
+
+var keys = []string{KEY_URL, KEY_ARTIST, KEY_TITLE, KEY_INDEX_ID, KEY_INDEX_NAME, KEY_SIMILARITY}
+
+// 原始数据中可作为作者 / 标题的字段, 按优先级
+var (
+	artistKeys = []string{"member_name", "author_name", "creator", "user_name", "twitter_user_handle", "pawoo_user_display_name", "company"}
+	titleKeys  = []string{"title", "eng_name", "jp_name", "material", "source"} // booru 的 source 为链接, 排在 material 之后
+)
+
+// FromResult 从搜索结果提取来源信息, 链接取第一个规范化链接
+func FromResult(r SauceNao.Result) Info {
+	info := Info{
+		IndexId:    r.Header.IndexId,
+		IndexName:  r.Header.IndexId.String(),
+		Similarity: r.Header.SimilarityFloat(),
+		Artist:     firstString(r.Data, artistKeys),
+		Title:      firstString(r.Data, titleKeys),
+	}
+	if urls := r.DecodeData().CanonicalURLs(); len(urls) > 0 {
+		info.Url = urls[0].String()
+	} else if ext, ok := r.Data["ext_urls"].([]any); ok && len(ext) > 0 {
+		info.Url, _ = ext[0].(string)
+	}
+	return info
+}
+
+// firstString 返回第一个非空且不是链接的字符串字段, 字符串数组以 ", " 连接
+func firstString(data map[string]any, keys []string) string {
+	for _, k := range keys {
+		switch v := data[k].(type) {
+		case string:
+			if v != "" && !isUrl(v) {
+				return v
+			}
+		case []any:
+			var ss []string
+			for _, e := range v {
+				if s, ok := e.(string); ok && s != "" {
+					ss = append(ss, s)
+				}
+			}
+			if len(ss) > 0 {
+				return strings.Join(ss, ", ")
+			}
+		}
+	}
+	return ""
+}
+
+func isUrl(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+func (info Info) get(key string) string {
+	switch key {
+	case KEY_URL:
+		return info.Url
+	case KEY_ARTIST:
+		return info.Artist
+	case KEY_TITLE:
+		return info.Title
+	case KEY_INDEX_ID:
+		return strconv.Itoa(int(info.IndexId))
+	case KEY_INDEX_NAME:
+		return info.IndexName
+	case KEY_SIMILARITY:
+		return strconv.FormatFloat(info.Similarity, 'f', 2, 64)
+	}
+	return ""
+}
+
+// set 未知字段与无法解析的数值忽略
+func (info *Info) set(key, value string) {
+	switch key {
+	case KEY_URL:
+		info.Url = value
+	case KEY_ARTIST:
+		info.Artist = value
+	case KEY_TITLE:
+		info.Title = value
+	case KEY_INDEX_ID:
+		id, _ := strconv.Atoi(value)
+		info.IndexId = db.IndexId(id)
+	case KEY_INDEX_NAME:
+		info.IndexName = value
+	case KEY_SIMILARITY:
+		info.Similarity, _ = strconv.ParseFloat(value, 64)
+	}
+}
+
+type format int
+
+const (
+	format_other format = iota
+	format_png
+	format_jpeg
+)
+
+func sniff(data []byte) format {
+	switch {
+	case bytes.HasPrefix(data, pngSignature):
+		return format_png
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		return format_jpeg
+	}
+	return format_other
+}
+
+// Write 按文件内容选择写入方式, PNG / JPEG 原地替换 (先写临时文件再重命名),
+// 其他格式写入 {path}[SIDECAR_EXT]. 再次写入会覆盖此前写入的信息
+func Write(path string, info Info) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var out []byte
+	switch sniff(data) {
+	case format_png:
+		out, err = EmbedPng(data, info)
+	case format_jpeg:
+		out, err = EmbedJpeg(data, info)
+	default:
+		return os.WriteFile(path+SIDECAR_EXT, MarshalXmp(info), 0o644)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return replaceFile(path, out)
+}
+
+// Read 读取 [Write] 写入的信息, 内嵌的优先, 其次 sidecar, 都没有时返回 [ErrNotTagged]
+func Read(path string) (*Info, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var info *Info
+	switch sniff(data) {
+	case format_png:
+		info, err = ReadPng(data)
+	case format_jpeg:
+		info, err = ReadJpeg(data)
+	default:
+		err = ErrNotTagged
+	}
+	if !errors.Is(err, ErrNotTagged) {
+		return info, err
+	}
+
+	xmp, e := os.ReadFile(path + SIDECAR_EXT)
+	if errors.Is(e, fs.ErrNotExist) {
+		return nil, ErrNotTagged
+	}
+	if e != nil {
+		return nil, e
+	}
+	return ParseXmp(xmp)
+}
+
+// Tagged 是否已写入过来源信息, 用于跳过已处理的文件
+func Tagged(path string) (bool, error) {
+	_, err := Read(path)
+	if errors.Is(err, ErrNotTagged) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// replaceFile 保留原文件权限
+func replaceFile(path string, data []byte) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if e := tmp.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), stat.Mode().Perm())
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,114 @@
+package meta
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+var testInfo = Info{
+	Url:        "https://www.pixiv.net/artworks/1",
+	Artist:     "作者 & <co>",
+	Title:      "title\nline2",
+	IndexId:    db.PIXIV,
+	IndexName:  db.PIXIV.String(),
+	Similarity: 92.5,
+}
+
+func TestRoundTrip(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	var pngBuf, jpegBuf bytes.Buffer
+	png.Encode(&pngBuf, img)
+	jpeg.Encode(&jpegBuf, img, nil)
+
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"a.png":  pngBuf.Bytes(),
+		"b.jpg":  jpegBuf.Bytes(),
+		"c.webp": []byte("RIFF....WEBP"),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, data, 0o644)
+
+		if tagged, err := Tagged(path); err != nil || tagged {
+			t.Fatalf("%s: Tagged = %v, %v", name, tagged, err)
+		}
+		// 写两次, 第二次应替换第一次
+		Write(path, Info{Title: "old"})
+		err := Write(path, testInfo)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		info, err := Read(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if *info != testInfo {
+			t.Errorf("%s: got %+v, want %+v", name, *info, testInfo)
+		}
+
+		out, _ := os.ReadFile(path)
+		switch name {
+		case "a.png":
+			_, err = png.Decode(bytes.NewReader(out))
+			if n := bytes.Count(out, []byte(PNG_KEYWORD_PREFIX+KEY_TITLE)); n != 1 {
+				t.Errorf("%s: %d title chunks", name, n)
+			}
+		case "b.jpg":
+			_, err = jpeg.Decode(bytes.NewReader(out))
+			if n := bytes.Count(out, xmpSignature); n != 1 {
+				t.Errorf("%s: %d xmp segments", name, n)
+			}
+		case "c.webp":
+			if !bytes.Equal(out, files[name]) {
+				t.Errorf("%s: file modified", name)
+			}
+		}
+		if err != nil {
+			t.Errorf("%s: decode after write: %v", name, err)
+		}
+	}
+}
+
+func TestFromResult(t *testing.T) {
+	r := SauceNao.Result{
+		Header: SauceNao.ResultHeader{Similarity: "91.20", IndexId: db.DANBOORU},
+		Data: map[string]any{
+			"danbooru_id": 123,
+			"creator":     "someone",
+			"material":    "original",
+		},
+	}
+	info := FromResult(r)
+	if info.Url != "https://danbooru.donmai.us/posts/123" || info.Artist != "someone" || info.Title != "original" || info.Similarity != 91.2 {
+		t.Errorf("got %+v", info)
+	}
+}
+
+// booru 的 source 是链接, 不作为标题
+func TestFromResultTitle(t *testing.T) {
+	for _, tt := range []struct {
+		id    db.IndexId
+		data  map[string]any
+		title string
+	}{
+		{db.DANBOORU, map[string]any{"material": "blue archive", "source": "https://twitter.com/a/status/1"}, "blue archive"},
+		{db.YANDERE, map[string]any{"material": "", "source": "https://i.pximg.net/img-original/img/1_p0.png"}, ""},
+		{db.GELBOORU, map[string]any{"source": "http://www.pixiv.net/member_illust.php?illust_id=1"}, ""},
+		{db.ANIME, map[string]any{"source": "Some Anime"}, "Some Anime"},
+		{db.PIXIV, map[string]any{"title": "t", "source": "s"}, "t"},
+	} {
+		info := FromResult(SauceNao.Result{Header: SauceNao.ResultHeader{IndexId: tt.id}, Data: tt.data})
+		if info.Title != tt.title {
+			t.Errorf("%s: title = %q, want %q", tt.id, info.Title, tt.title)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package meta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"strings"
+	"unicode/utf8"
+)
+
+// PNG_KEYWORD_PREFIX tEXt/iTXt 块的关键字前缀, 如 "SauceNAO:url"
+const PNG_KEYWORD_PREFIX = "SauceNAO:"
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+var errPngMalformed = errors.New("malformed png")
+
+type pngChunk struct {
+	typ  string
+	data []byte
+}
+
+func splitPng(data []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, errPngMalformed
+	}
+	var chunks []pngChunk
+	rest := data[len(pngSignature):]
+	for len(rest) > 0 {
+		if len(rest) < 12 {
+			return nil, errPngMalformed
+		}
+		n := binary.BigEndian.Uint32(rest)
+		if uint64(n) > uint64(len(rest)-12) {
+			return nil, errPngMalformed
+		}
+		c := pngChunk{typ: string(rest[4:8]), data: rest[8 : 8+n]}
+		chunks = append(chunks, c)
+		rest = rest[12+n:]
+		if c.typ == "IEND" {
+			break
+		}
+	}
+	if len(chunks) == 0 || chunks[0].typ != "IHDR" || chunks[len(chunks)-1].typ != "IEND" {
+		return nil, errPngMalformed
+	}
+	return chunks, nil
+}
+
+func appendPngChunk(b []byte, c pngChunk) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(c.data)))
+	start := len(b)
+	b = append(b, c.typ...)
+	b = append(b, c.data...)
+	return binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(b[start:]))
+}
+
+// textChunk 纯 ASCII 用 tEXt, 否则用 UTF-8 的 iTXt
+func textChunk(keyword, value string) pngChunk {
+	for i := 0; i < len(value); i++ {
+		if value[i] >= 0x80 {
+			// keyword \0 压缩标志 压缩方法 语言 \0 翻译后的关键字 \0 文本
+			data := append([]byte(keyword), 0, 0, 0, 0, 0)
+			return pngChunk{"iTXt", append(data, value...)}
+		}
+	}
+	return pngChunk{"tEXt", append(append([]byte(keyword), 0), value...)}
+}
+
+// parseTextChunk 返回关键字与文本, 压缩的 iTXt 不支持
+func parseTextChunk(c pngChunk) (keyword, value string, ok bool) {
+	keyword, rest, ok := strings.Cut(string(c.data), "\x00")
+	if !ok {
+		return "", "", false
+	}
+	switch c.typ {
+	case "tEXt":
+		return keyword, rest, true
+	case "iTXt":
+		if len(rest) < 2 || rest[0] != 0 {
+			return "", "", false
+		}
+		// 跳过语言与翻译后的关键字
+		_, rest, ok = strings.Cut(rest[2:], "\x00")
+		if ok {
+			_, rest, ok = strings.Cut(rest, "\x00")
+		}
+		if !ok || !utf8.ValidString(rest) {
+			return "", "", false
+		}
+		return keyword, rest, true
+	}
+	return "", "", false
+}
+
+func isOurPngChunk(c pngChunk) bool {
+	if c.typ != "tEXt" && c.typ != "iTXt" {
+		return false
+	}
+	return bytes.HasPrefix(c.data, []byte(PNG_KEYWORD_PREFIX))
+}
+
+// EmbedPng 在 IEND 之前写入文本块, 并移除此前写入的
+func EmbedPng(data []byte, info Info) ([]byte, error) {
+	chunks, err := splitPng(data)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(data)+512)
+	out = append(out, pngSignature...)
+	for _, c := range chunks {
+		if isOurPngChunk(c) {
+			continue
+		}
+		if c.typ == "IEND" {
+			for _, k := range keys {
+				if v := info.get(k); v != "" {
+					out = appendPngChunk(out, textChunk(PNG_KEYWORD_PREFIX+k, v))
+				}
+			}
+		}
+		out = appendPngChunk(out, c)
+	}
+	return out, nil
+}
+
+// ReadPng 读取 [EmbedPng] 写入的文本块, 没有时返回 [ErrNotTagged]
+func ReadPng(data []byte) (*Info, error) {
+	chunks, err := splitPng(data)
+	if err != nil {
+		return nil, err
+	}
+	info := &Info{}
+	found := false
+	for _, c := range chunks {
+		if !isOurPngChunk(c) {
+			continue
+		}
+		k, v, ok := parseTextChunk(c)
+		if ok {
+			info.set(strings.TrimPrefix(k, PNG_KEYWORD_PREFIX), v)
+			found = true
+		}
+	}
+	if !found {
+		return nil, ErrNotTagged
+	}
+	return info, nil
+}
@@ -0,0 +1,58 @@
+package meta
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+const XMP_NAMESPACE = "https://saucenao.com/xmp/1.0/"
+
+// MarshalXmp 生成 XMP packet, 字段写为 rdf:Description 上 saucenao 命名空间的属性
+func MarshalXmp(info Info) []byte {
+	var b bytes.Buffer
+	b.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString(`<x:xmpmeta xmlns:x="adobe:ns:meta/">` + "\n")
+	b.WriteString(`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` + "\n")
+	b.WriteString(`<rdf:Description rdf:about="" xmlns:saucenao="` + XMP_NAMESPACE + `"`)
+	for _, k := range keys {
+		b.WriteString("\n saucenao:" + k + `="`)
+		xml.EscapeText(&b, []byte(info.get(k)))
+		b.WriteString(`"`)
+	}
+	b.WriteString("/>\n</rdf:RDF>\n</x:xmpmeta>\n")
+	b.WriteString(`<?xpacket end="w"?>`)
+	return b.Bytes()
+}
+
+// ParseXmp 读取 saucenao 命名空间的属性, 没有时返回 [ErrNotTagged]
+func ParseXmp(data []byte) (*Info, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	info := &Info{}
+	found := false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break // EOF 或格式错误, 以已读到的为准
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		for _, a := range se.Attr {
+			if a.Name.Space == XMP_NAMESPACE {
+				info.set(a.Name.Local, a.Value)
+				found = true
+			}
+		}
+	}
+	if !found {
+		return nil, ErrNotTagged
+	}
+	return info, nil
+}
+
+// isOurXmp 判断 XMP packet 是否由 [MarshalXmp] 生成
+func isOurXmp(data []byte) bool {
+	return strings.Contains(string(data), XMP_NAMESPACE)
+}
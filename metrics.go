@@ -0,0 +1,269 @@
+package SauceNao
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 搜索结果的分类, 见 [Metrics]
+const (
+	OUTCOME_OK           = "ok"
+	OUTCOME_NO_MATCH     = "no_match" // 没有不低于最低相似度的结果
+	OUTCOME_RATE_LIMITED = "rate_limited"
+	OUTCOME_ERROR        = "error"
+)
+
+const (
+	UPSTREAM_SAUCENAO     = "saucenao"
+	UPSTREAM_FLARESOLVERR = "flaresolverr"
+)
+
+// 延迟直方图的上界 (秒), FlareSolverr 过 cf 可能需要数十秒
+var latencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Metrics 收集 [Client] 的运行指标, 以 Prometheus 文本格式输出, 零值可用, 并发安全.
+// 设置到 [Client.Metrics] 后生效, 实现了 [http.Handler] 可直接挂载为 /metrics
+type Metrics struct {
+	mu        sync.Mutex
+	searches  map[[2]string]uint64 // {outcome, 首个结果的 index_id}
+	latency   map[string]*histogram
+	forbidden uint64
+	bypass    map[string]uint64 // ok | error
+	cacheHit  uint64
+	cacheMiss uint64
+	quota     Quota
+}
+
+type histogram struct {
+	counts []uint64 // 与 latencyBuckets 对应, 非累积
+	sum    float64
+	count  uint64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+func (m *Metrics) init() {
+	if m.searches == nil {
+		m.searches = map[[2]string]uint64{}
+		m.latency = map[string]*histogram{}
+		m.bypass = map[string]uint64{}
+	}
+}
+
+// 以下方法的接收者可以为 nil, 此时不做任何事
+
+func (m *Metrics) observeSearch(resp *Response, err error) {
+	if m == nil {
+		return
+	}
+	outcome, index := OUTCOME_OK, ""
+	switch {
+	case IsRateLimited(err), errors.Is(err, ErrLongLimit):
+		outcome = OUTCOME_RATE_LIMITED
+	case err != nil:
+		outcome = OUTCOME_ERROR
+	case len(resp.Results) == 0 || resp.Results[0].Header.SimilarityFloat() < resp.Header.MinimumSimilarity:
+		outcome = OUTCOME_NO_MATCH
+	default:
+		index = strconv.Itoa(int(resp.Results[0].Header.IndexId))
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.searches[[2]string{outcome, index}]++
+}
+
+func (m *Metrics) observeLatency(upstream string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	h := m.latency[upstream]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+		m.latency[upstream] = h
+	}
+	s := d.Seconds()
+	if i, _ := slices.BinarySearch(latencyBuckets, s); i < len(latencyBuckets) {
+		h.counts[i]++
+	}
+	h.sum += s
+	h.count++
+}
+
+func (m *Metrics) observeForbidden() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.forbidden++
+}
+
+func (m *Metrics) observeBypass(err error) {
+	if m == nil {
+		return
+	}
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.bypass[result]++
+}
+
+func (m *Metrics) observeCache(hit bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hit {
+		m.cacheHit++
+	} else {
+		m.cacheMiss++
+	}
+}
+
+func (m *Metrics) observeQuota(q Quota) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quota = q
+}
+
+// WriteTo 以 Prometheus 文本格式 (version 0.0.4) 输出,
+// 写入 w 时不持有锁, 慢的 w 不会阻塞统计
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	m.render(&buf)
+	return buf.WriteTo(w)
+}
+
+func (m *Metrics) render(buf *bytes.Buffer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+
+	e := encoder{buf}
+
+	e.header("saucenao_searches_total", "counter", "Searches by outcome and index id of the top result.")
+	keys := make([][2]string, 0, len(m.searches))
+	for k := range m.searches {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b [2]string) int {
+		return strings.Compare(a[0]+"\x00"+a[1], b[0]+"\x00"+b[1])
+	})
+	for _, k := range keys {
+		e.sample("saucenao_searches_total", labels{"outcome", k[0], "index", k[1]}, float64(m.searches[k]))
+	}
+
+	e.header("saucenao_upstream_duration_seconds", "histogram", "Latency of requests to SauceNAO and FlareSolverr.")
+	for _, upstream := range []string{UPSTREAM_SAUCENAO, UPSTREAM_FLARESOLVERR} {
+		h := m.latency[upstream]
+		if h == nil {
+			continue
+		}
+		var cum uint64
+		for i, le := range latencyBuckets {
+			cum += h.counts[i]
+			e.sample("saucenao_upstream_duration_seconds_bucket", labels{"upstream", upstream, "le", formatFloat(le)}, float64(cum))
+		}
+		e.sample("saucenao_upstream_duration_seconds_bucket", labels{"upstream", upstream, "le", "+Inf"}, float64(h.count))
+		e.sample("saucenao_upstream_duration_seconds_sum", labels{"upstream", upstream}, h.sum)
+		e.sample("saucenao_upstream_duration_seconds_count", labels{"upstream", upstream}, float64(h.count))
+	}
+
+	e.header("saucenao_forbidden_total", "counter", "HTTP 403 responses from SauceNAO (Cloudflare challenges).")
+	e.sample("saucenao_forbidden_total", nil, float64(m.forbidden))
+
+	e.header("saucenao_cf_bypass_total", "counter", "Cloudflare bypass attempts via FlareSolverr by result.")
+	for _, result := range []string{"ok", "error"} {
+		e.sample("saucenao_cf_bypass_total", labels{"result", result}, float64(m.bypass[result]))
+	}
+
+	e.header("saucenao_cache_requests_total", "counter", "Response cache lookups by result.")
+	e.sample("saucenao_cache_requests_total", labels{"result", "hit"}, float64(m.cacheHit))
+	e.sample("saucenao_cache_requests_total", labels{"result", "miss"}, float64(m.cacheMiss))
+
+	if m.quota.Known() {
+		e.header("saucenao_quota_short_remaining", "gauge", "Last seen searches remaining in the 30s window.")
+		e.sample("saucenao_quota_short_remaining", nil, float64(m.quota.ShortRemaining))
+		e.header("saucenao_quota_long_remaining", "gauge", "Last seen searches remaining in the 24h window.")
+		e.sample("saucenao_quota_long_remaining", nil, float64(m.quota.LongRemaining))
+		e.header("saucenao_quota_short_limit", "gauge", "Searches allowed per 30s.")
+		e.sample("saucenao_quota_short_limit", nil, float64(m.quota.ShortLimit))
+		e.header("saucenao_quota_long_limit", "gauge", "Searches allowed per 24h.")
+		e.sample("saucenao_quota_long_limit", nil, float64(m.quota.LongLimit))
+		e.header("saucenao_quota_updated_timestamp_seconds", "gauge", "Unix time the quota was last seen.")
+		e.sample("saucenao_quota_updated_timestamp_seconds", nil, float64(m.quota.UpdatedAt.UnixMilli())/1000)
+	}
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// labels 依次为 name, value
+type labels []string
+
+type encoder struct {
+	w *bytes.Buffer
+}
+
+func (e encoder) header(name, typ, help string) {
+	e.w.WriteString("# HELP " + name + " " + strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help) + "\n")
+	e.w.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+func (e encoder) sample(name string, ls labels, v float64) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(ls) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(ls); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, `%s="%s"`, ls[i], escapeLabel(ls[i+1]))
+		}
+		b.WriteByte('}')
+	}
+	b.WriteString(" " + formatFloat(v) + "\n")
+	e.w.WriteString(b.String())
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,75 @@
+package SauceNao
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	forbidden := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if forbidden {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `{"header":{"short_limit":"4","long_limit":"100","short_remaining":3,"long_remaining":90,"minimum_similarity":50},
+"results":[{"header":{"similarity":"90.00","index_id":5},"data":{"pixiv_id":1}}]}`)
+	}))
+	defer srv.Close()
+
+	c := NewClient("key", srv.URL, 0, HIDE_NONE, nil)
+	c.Metrics = NewMetrics()
+	c.Cache = NewMemoryCache(8, time.Minute)
+	ctx := t.Context()
+	c.Post(ctx, []byte("a"))
+	forbidden = false
+	c.Post(ctx, []byte("a"))
+	c.Post(ctx, []byte("a"))
+
+	var b strings.Builder
+	c.Metrics.WriteTo(&b)
+	out := b.String()
+	for _, line := range []string{
+		`saucenao_searches_total{outcome="error",index=""} 1`,
+		`saucenao_searches_total{outcome="ok",index="5"} 2`,
+		`saucenao_upstream_duration_seconds_count{upstream="saucenao"} 2`,
+		`saucenao_upstream_duration_seconds_bucket{upstream="saucenao",le="+Inf"} 2`,
+		`saucenao_forbidden_total 1`,
+		`saucenao_cf_bypass_total{result="error"} 1`,
+		`saucenao_cache_requests_total{result="hit"} 1`,
+		`saucenao_cache_requests_total{result="miss"} 2`,
+		`saucenao_quota_long_remaining 90`,
+		`# TYPE saucenao_upstream_duration_seconds histogram`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, out)
+		}
+	}
+}
+
+// writerFunc 写入时调用 f
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+// 写入慢的 writer 时不阻塞统计
+func TestMetricsWriteToUnlocked(t *testing.T) {
+	m := NewMetrics()
+	done := make(chan struct{})
+	m.WriteTo(writerFunc(func(p []byte) (int, error) {
+		go func() {
+			m.observeForbidden()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Error("observeForbidden blocked by WriteTo")
+		}
+		return len(p), nil
+	}))
+}
@@ -0,0 +1,105 @@
+package SauceNao
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// HideLevel 对应 api 的 hide 参数
+type HideLevel int
+
+const (
+	HIDE_NONE         HideLevel = 0 // 显示全部结果
+	HIDE_EXPLICIT     HideLevel = 1 // 隐藏预期为露骨的结果
+	HIDE_SUSPECTED    HideLevel = 2 // 隐藏预期及疑似露骨的结果
+	HIDE_ALL_BUT_SAFE HideLevel = 3 // 只保留预期安全的结果
+)
+
+func (hl HideLevel) String() string {
+	switch hl {
+	case HIDE_NONE:
+		return "none"
+	case HIDE_EXPLICIT:
+		return "explicit"
+	case HIDE_SUSPECTED:
+		return "suspected"
+	case HIDE_ALL_BUT_SAFE:
+		return "all-but-safe"
+	default:
+		return "HideLevel(" + strconv.Itoa(int(hl)) + ")"
+	}
+}
+
+// Option 单次请求的参数, 覆盖 [Client] 上的同名设置
+type Option func(*requestOptions)
+
+type requestOptions struct {
+	numRes     int
+	dbMask     uint64
+	hide       HideLevel
+	safeFilter bool
+	html       bool // 见 [Client.HtmlFallback]
+	rawBody    bool // 见 [Client.KeepRawBody]
+}
+
+func (c *Client) requestOptions(opts []Option) *requestOptions {
+	ro := &requestOptions{
+		numRes:     c.NumRes,
+		dbMask:     c.DbMask,
+		hide:       c.Hide,
+		safeFilter: c.SafeFilter,
+		html:       c.ApiKey == "" && c.HtmlFallback,
+		rawBody:    c.KeepRawBody,
+	}
+	for _, opt := range opts {
+		opt(ro)
+	}
+	return ro
+}
+
+// WithNumRes 覆盖 [Client.NumRes]
+func WithNumRes(numRes int) Option {
+	return func(ro *requestOptions) { ro.numRes = numRes }
+}
+
+// WithDbMask 覆盖 [Client.DbMask]
+func WithDbMask(mask uint64) Option {
+	return func(ro *requestOptions) { ro.dbMask = mask }
+}
+
+// WithHide 覆盖 [Client.Hide]
+func WithHide(level HideLevel) Option {
+	return func(ro *requestOptions) { ro.hide = level }
+}
+
+// WithSafeFilter 覆盖 [Client.SafeFilter]
+func WithSafeFilter(enable bool) Option {
+	return func(ro *requestOptions) { ro.safeFilter = enable }
+}
+
+// WithRawBody 覆盖 [Client.KeepRawBody]
+func WithRawBody(keep bool) Option {
+	return func(ro *requestOptions) { ro.rawBody = keep }
+}
+
+// setQuery 写入与输出格式无关的公共参数
+func (ro *requestOptions) setQuery(query url.Values) {
+	if ro.numRes > 0 {
+		query.Add("numres", strconv.Itoa(ro.numRes))
+	}
+	if ro.dbMask != 0 {
+		query.Add("dbmask", strconv.FormatUint(ro.dbMask, 10))
+	}
+	query.Add("hide", strconv.Itoa(int(ro.hide)))
+}
+
+// filterHidden 丢弃 Header.Hidden 非 0 的结果
+func filterHidden(resp *Response) {
+	results := resp.Results[:0]
+	for _, r := range resp.Results {
+		if !r.Header.IsHidden() {
+			results = append(results, r)
+		}
+	}
+	resp.Results = results
+}
@@ -0,0 +1,58 @@
+package SauceNao
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestHideAndSafeFilter(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		fmt.Fprint(w, `{"header":{"status":0,"minimum_similarity":50},"results":[
+{"header":{"similarity":"90.00","index_id":5,"hidden":0},"data":{"pixiv_id":1}},
+{"header":{"similarity":"80.00","index_id":9,"hidden":1},"data":{"danbooru_id":2}},
+{"header":{"similarity":"70.00","index_id":12,"hidden":0},"data":{"yandere_id":3}}]}`)
+	}))
+	defer srv.Close()
+
+	c := NewClient("key", srv.URL, 0, HIDE_EXPLICIT, nil)
+	c.SafeFilter = true
+	for i, tt := range []struct {
+		opts    []Option
+		get     bool
+		hide    string
+		results int
+	}{
+		{nil, false, "1", 2},
+		{nil, true, "1", 2},
+		{[]Option{WithHide(HIDE_ALL_BUT_SAFE)}, false, "3", 2},
+		{[]Option{WithHide(HIDE_NONE), WithSafeFilter(false)}, true, "0", 3},
+		{[]Option{WithHide(HIDE_SUSPECTED), WithSafeFilter(false)}, false, "2", 3},
+	} {
+		var resp *Response
+		var err error
+		if tt.get {
+			resp, err = c.Get(t.Context(), fmt.Sprintf("https://example.com/%d.png", i), tt.opts...)
+		} else {
+			resp, err = c.Post(t.Context(), fmt.Appendf(nil, "image %d", i), tt.opts...)
+		}
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if got := query.Get("hide"); got != tt.hide {
+			t.Errorf("%d: hide = %q, want %q", i, got, tt.hide)
+		}
+		if len(resp.Results) != tt.results {
+			t.Errorf("%d: got %d results, want %d", i, len(resp.Results), tt.results)
+		}
+		for _, r := range resp.Results {
+			if tt.results == 2 && r.Header.IsHidden() {
+				t.Errorf("%d: hidden result kept: %+v", i, r.Header)
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package SauceNao
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SHORT_WINDOW = 30 * time.Second
+	LONG_WINDOW  = 24 * time.Hour
+)
+
+var ErrLongLimit = errors.New("daily search limit reached")
+
+// Quota 从最近一次响应中得知的搜索次数限制
+type Quota struct {
+	ShortLimit     int       `json:"short_limit"` // 30s
+	LongLimit      int       `json:"long_limit"`  // 24h
+	ShortRemaining int       `json:"short_remaining"`
+	LongRemaining  int       `json:"long_remaining"`
+	UpdatedAt      time.Time `json:"updated_at"` // 零值表示尚未得知
+}
+
+// Known 是否已从响应中得知限额
+func (q Quota) Known() bool {
+	return !q.UpdatedAt.IsZero()
+}
+
+// Quota 返回最近一次得知的限额
+func (c *Client) Quota() Quota {
+	c.quotaMu.Lock()
+	defer c.quotaMu.Unlock()
+	return c.quota
+}
+
+// updateQuota 根据 json 响应头更新限额, html 响应没有这些字段, 不更新
+func (c *Client) updateQuota(h *ResponseHeader) {
+	if h.ShortLimit == "" && h.LongLimit == "" {
+		return
+	}
+	shortLimit, _ := strconv.Atoi(h.ShortLimit)
+	longLimit, _ := strconv.Atoi(h.LongLimit)
+	c.quotaMu.Lock()
+	ev := QuotaEvent{Previous: c.quota}
+	c.quota = Quota{
+		ShortLimit:     shortLimit,
+		LongLimit:      longLimit,
+		ShortRemaining: h.ShortRemaining,
+		LongRemaining:  h.LongRemaining,
+		UpdatedAt:      time.Now(),
+	}
+	ev.Quota = c.quota
+	c.quotaMu.Unlock()
+	c.Metrics.observeQuota(ev.Quota)
+	c.logger().Debug("quota updated", LOG_SHORT_REMAINING, ev.Quota.ShortRemaining, LOG_LONG_REMAINING, ev.Quota.LongRemaining)
+	c.Hooks.quotaUpdate(ev)
+}
+
+// quotaExceeded 收到 429 时根据提示标记对应限额已用尽
+func (c *Client) quotaExceeded(body string) {
+	c.quotaMu.Lock()
+	ev := QuotaEvent{Previous: c.quota, Exceeded: true}
+	if strings.Contains(strings.ToLower(body), "daily") {
+		c.quota.LongRemaining = 0
+	}
+	c.quota.ShortRemaining = 0
+	c.quota.UpdatedAt = time.Now()
+	ev.Quota = c.quota
+	c.quotaMu.Unlock()
+	c.Metrics.observeQuota(ev.Quota)
+	c.logger().Warn("quota exceeded", LOG_SHORT_REMAINING, ev.Quota.ShortRemaining, LOG_LONG_REMAINING, ev.Quota.LongRemaining)
+	c.Hooks.quotaUpdate(ev)
+}
+
+// WaitQuota 30s 限额用尽时等待窗口结束, 24h 限额用尽时返回 [ErrLongLimit]
+func (c *Client) WaitQuota(ctx context.Context) error {
+	q := c.Quota()
+	if !q.Known() {
+		return nil
+	}
+	if q.LongRemaining <= 0 && time.Since(q.UpdatedAt) < LONG_WINDOW {
+		return ErrLongLimit
+	}
+	if q.ShortRemaining > 0 {
+		return nil
+	}
+	wait := SHORT_WINDOW - time.Since(q.UpdatedAt)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,96 @@
+package SauceNao
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+// RankWeights 结果排序的权重
+type RankWeights struct {
+	Index               map[db.IndexId]float64 // 各索引的加分, 未列出的索引不加分
+	MarginWeight        float64                // (相似度 - MinimumSimilarity) 的系数
+	BelowMinimumPenalty float64                // 相似度低于 MinimumSimilarity 时的扣分
+	HiddenPenalty       float64                // Header.Hidden 非 0 时的扣分
+}
+
+// DefaultRankWeights 原作平台优先于 booru 转载
+var DefaultRankWeights = RankWeights{
+	Index: map[db.IndexId]float64{
+		db.PIXIV:       10,
+		db.TWITTER:     8,
+		db.SKEB:        7,
+		db.KEMONO:      6,
+		db.DEVIANTART:  6,
+		db.ARTSTATION:  6,
+		db.NIJIE:       5,
+		db.SEIGA:       5,
+		db.FURAFFINITY: 5,
+		db.PAWOO:       4,
+		db.DANBOORU:    3,
+		db.YANDERE:     2,
+		db.GELBOORU:    2,
+		db.KONACHAN:    1,
+		db.SANKAKU:     1,
+	},
+	MarginWeight:        0.1,
+	BelowMinimumPenalty: 20,
+	HiddenPenalty:       50,
+}
+
+// ScoreBreakdown 单个结果的得分构成
+type ScoreBreakdown struct {
+	Similarity   float64 // 相似度本身
+	Margin       float64 // 高出 MinimumSimilarity 部分的加分
+	IndexBonus   float64 // 索引偏好加分
+	BelowMinimum float64 // 低于 MinimumSimilarity 的扣分, <= 0
+	Hidden       float64 // Hidden 结果的扣分, <= 0
+	Total        float64
+}
+
+func (sb ScoreBreakdown) String() string {
+	return fmt.Sprintf("%.2f = similarity %.2f + margin %.2f + index %.2f + below_minimum %.2f + hidden %.2f",
+		sb.Total, sb.Similarity, sb.Margin, sb.IndexBonus, sb.BelowMinimum, sb.Hidden)
+}
+
+type RankedResult struct {
+	Result
+	Score ScoreBreakdown
+}
+
+// Rank 见 [RankResults], weights 为 nil 时使用 [DefaultRankWeights]
+func (r *Response) Rank(weights *RankWeights) []RankedResult {
+	return RankResults(r.Results, r.Header.MinimumSimilarity, weights)
+}
+
+// RankResults 按综合得分降序排列结果,
+// 得分 = 相似度 + 高出最低相似度部分 * MarginWeight + 索引加分 - 扣分
+func RankResults(results []Result, minimumSimilarity float64, weights *RankWeights) []RankedResult {
+	if weights == nil {
+		weights = &DefaultRankWeights
+	}
+	ranked := make([]RankedResult, len(results))
+	for i, r := range results {
+		sim := r.Header.SimilarityFloat()
+		sb := ScoreBreakdown{
+			Similarity: sim,
+			IndexBonus: weights.Index[r.Header.IndexId],
+		}
+		if sim >= minimumSimilarity {
+			sb.Margin = (sim - minimumSimilarity) * weights.MarginWeight
+		} else {
+			sb.BelowMinimum = -weights.BelowMinimumPenalty
+		}
+		if r.Header.IsHidden() {
+			sb.Hidden = -weights.HiddenPenalty
+		}
+		sb.Total = sb.Similarity + sb.Margin + sb.IndexBonus + sb.BelowMinimum + sb.Hidden
+		ranked[i] = RankedResult{Result: r, Score: sb}
+	}
+	slices.SortStableFunc(ranked, func(a, b RankedResult) int {
+		return cmp.Compare(b.Score.Total, a.Score.Total)
+	})
+	return ranked
+}
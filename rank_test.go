@@ -0,0 +1,113 @@
+package SauceNao
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+func rankResult(id db.IndexId, similarity string, hidden int) Result {
+	return Result{Header: ResultHeader{IndexId: id, Similarity: similarity, Hidden: hidden}}
+}
+
+func TestRankResults(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		results []Result
+		minimum float64
+		weights *RankWeights
+		want    []int // 排序后结果在输入中的下标
+	}{
+		{
+			name:    "index preference beats slightly higher similarity",
+			results: []Result{rankResult(db.DANBOORU, "85.00", 0), rankResult(db.PIXIV, "80.00", 0)},
+			minimum: 50,
+			want:    []int{1, 0},
+		},
+		{
+			name:    "large similarity gap beats index preference",
+			results: []Result{rankResult(db.PIXIV, "60.00", 0), rankResult(db.DANBOORU, "90.00", 0)},
+			minimum: 50,
+			want:    []int{1, 0},
+		},
+		{
+			name:    "ties keep input order",
+			results: []Result{rankResult(db.GELBOORU, "70.00", 0), rankResult(db.YANDERE, "70.00", 0), rankResult(db.GELBOORU, "70.00", 0)},
+			minimum: 50,
+			want:    []int{0, 1, 2},
+		},
+		{
+			name:    "below minimum is penalized",
+			results: []Result{rankResult(db.PIXIV, "45.00", 0), rankResult(db.DANBOORU, "60.00", 0)},
+			minimum: 50,
+			want:    []int{1, 0},
+		},
+		{
+			name:    "hidden is penalized",
+			results: []Result{rankResult(db.PIXIV, "95.00", 1), rankResult(db.GELBOORU, "70.00", 0)},
+			minimum: 50,
+			want:    []int{1, 0},
+		},
+		{
+			name:    "custom weights",
+			results: []Result{rankResult(db.PIXIV, "90.00", 0), rankResult(db.DANBOORU, "80.00", 0)},
+			minimum: 50,
+			weights: &RankWeights{Index: map[db.IndexId]float64{db.DANBOORU: 20}},
+			want:    []int{1, 0},
+		},
+		{
+			name:    "zero weights rank by similarity",
+			results: []Result{rankResult(db.PIXIV, "40.00", 1), rankResult(db.DANBOORU, "80.00", 0), rankResult(db.TWITTER, "60.00", 0)},
+			minimum: 50,
+			weights: &RankWeights{},
+			want:    []int{1, 2, 0},
+		},
+	} {
+		ranked := RankResults(tt.results, tt.minimum, tt.weights)
+		// 相同 header 的结果按出现顺序对应
+		var got []int
+		used := make([]bool, len(tt.results))
+		for _, rr := range ranked {
+			for i, r := range tt.results {
+				if !used[i] && r.Header == rr.Header {
+					used[i] = true
+					got = append(got, i)
+					break
+				}
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: order = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRankBreakdown(t *testing.T) {
+	resp := &Response{
+		Header:  ResponseHeader{MinimumSimilarity: 50},
+		Results: []Result{rankResult(db.PIXIV, "50.00", 0), rankResult(db.DANBOORU, "40.00", 1)},
+	}
+	ranked := resp.Rank(nil)
+	w := DefaultRankWeights
+	for _, tt := range []struct {
+		got, want ScoreBreakdown
+	}{
+		// 恰好等于最低相似度时不扣分
+		{ranked[0].Score, ScoreBreakdown{Similarity: 50, IndexBonus: w.Index[db.PIXIV], Total: 50 + w.Index[db.PIXIV]}},
+		{ranked[1].Score, ScoreBreakdown{
+			Similarity:   40,
+			IndexBonus:   w.Index[db.DANBOORU],
+			BelowMinimum: -w.BelowMinimumPenalty,
+			Hidden:       -w.HiddenPenalty,
+			Total:        40 + w.Index[db.DANBOORU] - w.BelowMinimumPenalty - w.HiddenPenalty,
+		}},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("score = %+v, want %+v", tt.got, tt.want)
+		}
+	}
+	if ranked[0].Header.IndexId != db.PIXIV {
+		t.Errorf("first = %d", ranked[0].Header.IndexId)
+	}
+}
@@ -0,0 +1,231 @@
+// Package rename 按 text/template 模板以搜索结果重命名文件
+//
+// 模板的数据为 [Fields] 返回的 map, 含结果数据结构体 (见 db/structs.go) 的所有字段,
+// 如 {{.Index}}_{{.PixivId}}_{{.MemberName}}_{{.Title}}, 当前索引没有的字段为空字符串
+package rename
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+)
+
+// DEFAULT_MAX_LEN 文件名 (不含扩展名) 的最大字节数, 多数文件系统限制为 255
+const DEFAULT_MAX_LEN = 200
+
+var ErrEmptyName = errors.New("template produced an empty name")
+
+// Parse 解析模板, 缺失的字段渲染为空字符串
+func Parse(text string) (*template.Template, error) {
+	return template.New("rename").Option("missingkey=zero").Parse(text)
+}
+
+// Fields 模板可用的字段:
+//   - 结果数据结构体的所有导出字段, 切片以 "," 连接
+//   - Index: 索引名, IndexId, Similarity: 如 "92.50", Url: 第一个规范化链接
+//   - Name: 原文件名 (不含扩展名), Ext: 原扩展名 (含点)
+//
+// 无法解码的结果 ([db.ResultDataUnknown]) 使用原始数据, 键名转为 PixivId 的形式
+func Fields(r SauceNao.Result, path string) map[string]string {
+	fields := map[string]string{}
+	data := r.DecodeData()
+	rv := reflect.Indirect(reflect.ValueOf(data))
+	if rv.Kind() == reflect.Struct {
+		rt := rv.Type()
+		for i := range rt.NumField() {
+			f := rt.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if f.Name == "Raw" {
+				for k, v := range rv.Field(i).Interface().(map[string]any) {
+					fields[camelCase(k)] = format(reflect.ValueOf(v))
+				}
+				continue
+			}
+			fields[f.Name] = format(rv.Field(i))
+		}
+	}
+
+	fields["Index"] = r.Header.IndexId.String()
+	fields["IndexId"] = strconv.Itoa(int(r.Header.IndexId))
+	fields["Similarity"] = r.Header.Similarity
+	if urls := data.CanonicalURLs(); len(urls) > 0 {
+		fields["Url"] = urls[0].String()
+	}
+	ext := filepath.Ext(path)
+	fields["Name"] = strings.TrimSuffix(filepath.Base(path), ext)
+	fields["Ext"] = ext
+	return fields
+}
+
+func format(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		parts := make([]string, 0, v.Len())
+		for i := range v.Len() {
+			parts = append(parts, format(v.Index(i)))
+		}
+		return strings.Join(parts, ",")
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return ""
+		}
+		return format(v.Elem())
+	case reflect.Float32, reflect.Float64:
+		// json 解码的整数为 float64
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// camelCase "pixiv_id" -> "PixivId"
+func camelCase(s string) string {
+	var b strings.Builder
+	for part := range strings.SplitSeq(s, "_") {
+		r, n := utf8.DecodeRuneInString(part)
+		if n == 0 {
+			continue
+		}
+		b.WriteRune(unicode.ToUpper(r))
+		b.WriteString(part[n:])
+	}
+	return b.String()
+}
+
+// Windows 保留的设备名
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// Sanitize 替换各文件系统不允许的字符, 去掉首尾的空格与点, 截断到 maxLen 字节
+func Sanitize(name string, maxLen int) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x20, r == 0x7F, r == utf8.RuneError:
+			return '_'
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, " .")
+	if maxLen > 0 && len(name) > maxLen {
+		// 不截断在多字节字符中间
+		cut := maxLen
+		for cut > 0 && !utf8.RuneStart(name[cut]) {
+			cut--
+		}
+		name = strings.TrimRight(name[:cut], " .")
+	}
+	if reservedNames[strings.ToUpper(name)] {
+		name = "_" + name
+	}
+	return name
+}
+
+type Renamer struct {
+	Template *template.Template
+	DryRun   bool // 只生成报告, 不修改文件
+	MaxLen   int  // 默认 [DEFAULT_MAX_LEN]
+}
+
+// Item 待重命名的文件与选定的结果
+type Item struct {
+	Path   string
+	Result SauceNao.Result
+}
+
+// Op 一个文件的重命名结果, To 与 From 相同时表示无需改名
+type Op struct {
+	From string
+	To   string
+	Err  error
+}
+
+func (op Op) String() string {
+	if op.Err != nil {
+		return fmt.Sprintf("%s: %v", op.From, op.Err)
+	}
+	if op.From == op.To {
+		return fmt.Sprintf("%s: unchanged", op.From)
+	}
+	return fmt.Sprintf("%s -> %s", op.From, op.To)
+}
+
+// Name 返回新的文件名 (不含目录), 原扩展名总会保留
+func (rn *Renamer) Name(r SauceNao.Result, path string) (string, error) {
+	var b strings.Builder
+	err := rn.Template.Execute(&b, Fields(r, path))
+	if err != nil {
+		return "", err
+	}
+	maxLen := rn.MaxLen
+	if maxLen <= 0 {
+		maxLen = DEFAULT_MAX_LEN
+	}
+	name := Sanitize(b.String(), maxLen)
+	if name == "" {
+		return "", ErrEmptyName
+	}
+	return name + filepath.Ext(path), nil
+}
+
+// Rename 依次重命名, 与已有文件或本批次中先处理的文件重名时追加 "_2", "_3" 等.
+// 单个文件的错误记录在 [Op.Err] 中, 不中断其余文件
+func (rn *Renamer) Rename(items []Item) []Op {
+	ops := make([]Op, 0, len(items))
+	taken := map[string]bool{} // 本批次已占用的路径
+	freed := map[string]bool{} // 本批次已移走的路径, dry-run 时用于判断重名
+	for _, it := range items {
+		from := filepath.Clean(it.Path)
+		op := Op{From: from}
+		name, err := rn.Name(it.Result, from)
+		if err != nil {
+			op.Err = err
+			ops = append(ops, op)
+			continue
+		}
+
+		dir := filepath.Dir(from)
+		ext := filepath.Ext(name)
+		base := strings.TrimSuffix(name, ext)
+		for n := 2; ; n++ {
+			to := filepath.Join(dir, name)
+			if to == from || (!taken[to] && (freed[to] || !exists(to))) {
+				op.To = to
+				break
+			}
+			name = base + "_" + strconv.Itoa(n) + ext
+		}
+		if op.To != from && !rn.DryRun {
+			op.Err = os.Rename(from, op.To)
+		}
+		if op.Err == nil {
+			freed[from] = op.To != from
+			delete(freed, op.To)
+			taken[op.To] = true
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
@@ -0,0 +1,73 @@
+package rename
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+func pixivResult(title string) SauceNao.Result {
+	return SauceNao.Result{
+		Header: SauceNao.ResultHeader{Similarity: "92.50", IndexId: db.PIXIV},
+		Data:   map[string]any{"pixiv_id": 123, "member_name": "someone", "title": title},
+	}
+}
+
+func TestName(t *testing.T) {
+	tmpl, err := Parse("{{.IndexId}}_{{.PixivId}}_{{.MemberName}}_{{.Title}}{{.TweetId}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rn := &Renamer{Template: tmpl}
+	name, err := rn.Name(pixivResult(`a/b: "c"?`), "dir/x.PNG")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "5_123_someone_a_b_ _c__.PNG"; name != want {
+		t.Errorf("got %q, want %q", name, want)
+	}
+
+	if got := Sanitize(" 作者作品. ", 7); got != "作者" {
+		t.Errorf("Sanitize = %q", got)
+	}
+	if got := Sanitize("con", 0); got != "_con" {
+		t.Errorf("Sanitize = %q", got)
+	}
+}
+
+func TestRenameCollision(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.jpg", "b.jpg", "c.jpg", "t.jpg"} {
+		os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644)
+	}
+	tmpl, _ := Parse("{{.Title}}")
+	items := []Item{
+		{filepath.Join(dir, "a.jpg"), pixivResult("t")},
+		{filepath.Join(dir, "b.jpg"), pixivResult("t")},
+		{filepath.Join(dir, "c.jpg"), pixivResult("")},
+	}
+
+	want := []string{"t_2.jpg", "t_3.jpg", ""}
+	for _, dryRun := range []bool{true, false} {
+		rn := &Renamer{Template: tmpl, DryRun: dryRun}
+		ops := rn.Rename(items)
+		for i, op := range ops {
+			if want[i] == "" {
+				if op.Err != ErrEmptyName {
+					t.Errorf("dry-run %v: %v", dryRun, op)
+				}
+				continue
+			}
+			if op.Err != nil || filepath.Base(op.To) != want[i] {
+				t.Errorf("dry-run %v: %v, want %s", dryRun, op, want[i])
+			}
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "t_3.jpg"))
+	if err != nil || string(data) != "b.jpg" {
+		t.Errorf("t_3.jpg = %q, %v", data, err)
+	}
+}
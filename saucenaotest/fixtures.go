@@ -0,0 +1,95 @@
+package saucenaotest
+
+import (
+	"fmt"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+// fixtureData 各索引的示例数据, 字段与真实响应一致
+var fixtureData = map[db.IndexId]map[string]any{
+	db.PIXIV: {
+		"ext_urls":    []any{"https://www.pixiv.net/member_illust.php?mode=medium&illust_id=100000001"},
+		"title":       "fixture title",
+		"pixiv_id":    100000001,
+		"member_name": "fixture artist",
+		"member_id":   2000001,
+	},
+	db.DANBOORU: {
+		"ext_urls":    []any{"https://danbooru.donmai.us/post/show/3000001"},
+		"danbooru_id": 3000001,
+		"gelbooru_id": 4000001,
+		"creator":     "fixture artist",
+		"material":    "original",
+		"characters":  "fixture character",
+		"source":      "https://i.pximg.net/img-original/img/2020/01/01/00/00/00/100000001_p0.png",
+	},
+	db.YANDERE: {
+		"ext_urls":   []any{"https://yande.re/post/show/5000001"},
+		"yandere_id": 5000001,
+		"creator":    "fixture artist",
+		"material":   "original",
+		"characters": "fixture character",
+		"source":     "https://twitter.com/fixture_artist/status/1000000000000000001",
+	},
+	db.GELBOORU: {
+		"ext_urls":    []any{"https://gelbooru.com/index.php?page=post&s=view&id=4000001"},
+		"gelbooru_id": 4000001,
+		"creator":     "fixture artist",
+		"material":    "original",
+		"characters":  "fixture character",
+		"source":      "",
+	},
+	db.ANIME: {
+		"ext_urls":   []any{"https://anidb.net/anime/6000001"},
+		"source":     "fixture anime",
+		"anidb_aid":  6000001,
+		"anilist_id": 7000001,
+		"mal_id":     8000001,
+		"part":       "1",
+		"year":       "2020-2020",
+		"est_time":   "00:12:34 / 00:24:00",
+	},
+	db.DEVIANTART: {
+		"ext_urls":    []any{"https://deviantart.com/view/900000001"},
+		"title":       "fixture title",
+		"da_id":       "900000001",
+		"author_name": "fixture-artist",
+		"author_url":  "https://www.deviantart.com/fixture-artist",
+	},
+	db.TWITTER: {
+		"ext_urls":            []any{"https://twitter.com/i/web/status/1000000000000000001"},
+		"created_at":          "2020-01-01T00:00:00Z",
+		"tweet_id":            "1000000000000000001",
+		"twitter_user_id":     "10000001",
+		"twitter_user_handle": "fixture_artist",
+	},
+}
+
+// Fixture 返回指定索引的示例结果, 没有示例数据的索引 data 为空
+func Fixture(index db.IndexId, similarity float64) SauceNao.Result {
+	data := map[string]any{}
+	for k, v := range fixtureData[index] {
+		data[k] = v
+	}
+	return SauceNao.Result{
+		Header: SauceNao.ResultHeader{
+			Similarity: fmt.Sprintf("%.2f", similarity),
+			Thumbnail:  fmt.Sprintf("https://img3.saucenao.com/res/fixture/%d.jpg", index),
+			IndexId:    index,
+			IndexName:  fmt.Sprintf("Index #%d: %s - fixture.jpg", index, index),
+		},
+		Data: data,
+	}
+}
+
+// DefaultFixtures 每个有示例数据的索引一个结果, 相似度依次递减
+func DefaultFixtures() []SauceNao.Result {
+	order := []db.IndexId{db.PIXIV, db.TWITTER, db.DANBOORU, db.YANDERE, db.GELBOORU, db.DEVIANTART, db.ANIME}
+	results := make([]SauceNao.Result, 0, len(order))
+	for i, index := range order {
+		results = append(results, Fixture(index, 95-float64(i)*8))
+	}
+	return results
+}
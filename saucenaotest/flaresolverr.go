@@ -0,0 +1,309 @@
+package saucenaotest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	fs "github.com/Miuzarte/FlareSolverr-go"
+)
+
+const (
+	SOLVER_PATH    = "/v1"
+	SOLVER_VERSION = "saucenaotest"
+	USER_AGENT     = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 saucenaotest"
+	CLEARANCE      = "saucenaotest-clearance" // 配对时默认要求的 [CLEARANCE_COOKIE] 值
+)
+
+// FlareSolverr 说 FlareSolverr /v1 协议的假服务器, 支持 request.get, request.post 与 sessions.*.
+// 收到请求后带上 cookies 与 UserAgent 实际访问目标链接, 把状态码, 响应体与 cookies 作为 solution 返回
+//
+//	srv := saucenaotest.NewServer()
+//	solver := saucenaotest.NewFlareSolverr(srv)
+//	client := srv.Client()
+//	client.FlareSolverrClient = solver.Client()
+type FlareSolverr struct {
+	*httptest.Server
+
+	Target    *Server       // 配对的假 SauceNAO 服务器, 解决时返回其要求的 [CLEARANCE_COOKIE]
+	UserAgent string        // 默认 [USER_AGENT]
+	Cookies   []fs.Cookie   // 额外返回的 cookies
+	Delay     time.Duration // 每次解决前等待, 超过请求的 maxTimeout 时按超时失败
+
+	mu       sync.Mutex
+	faults   []SolverFault
+	requests []SolverRequest
+	sessions []string
+}
+
+// SolverRequest 收到的一次命令
+type SolverRequest struct {
+	Cmd        string
+	Url        string
+	MaxTimeout int         // 毫秒
+	Cookies    []fs.Cookie // 请求中附带的 cookies
+	PostData   string
+	Session    string
+}
+
+// SolverFault 下一次 request.* 命令的失败, 见 [FlareSolverr.Inject]
+type SolverFault struct {
+	Message string // status 为 "error" 时的 message
+	Status  int    // 非 0 时命令成功, 但 solution.status 为此值, Message 作为 solution.response
+}
+
+// NewFlareSolverr 启动并与 target 配对, target 为 nil 时不配对
+func NewFlareSolverr(target *Server) *FlareSolverr {
+	s := NewUnstartedFlareSolverr(target)
+	s.Start()
+	return s
+}
+
+// NewUnstartedFlareSolverr 可在启动前修改字段.
+// target 未设置 clearance 时设为 [CLEARANCE], 此后 target 只接受带有该 cookie 的请求
+func NewUnstartedFlareSolverr(target *Server) *FlareSolverr {
+	s := &FlareSolverr{
+		Target:    target,
+		UserAgent: USER_AGENT,
+	}
+	if target != nil && target.Clearance() == "" {
+		target.SetClearance(CLEARANCE)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(SOLVER_PATH, s.handle)
+	s.Server = httptest.NewUnstartedServer(mux)
+	return s
+}
+
+// Endpoint 即 FlareSolverr 的 /v1 地址
+func (s *FlareSolverr) Endpoint() string {
+	return s.URL + SOLVER_PATH
+}
+
+// Client 返回指向此服务器的 FlareSolverr client
+func (s *FlareSolverr) Client() *fs.Client {
+	return fs.NewClient(s.Endpoint())
+}
+
+// Inject 之后的 request.* 命令依次失败, 用完后恢复正常
+func (s *FlareSolverr) Inject(faults ...SolverFault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, faults...)
+}
+
+// Requests 返回收到的所有命令
+func (s *FlareSolverr) Requests() []SolverRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+type solverRequest struct {
+	Cmd        string      `json:"cmd"`
+	Url        string      `json:"url"`
+	MaxTimeout int         `json:"maxTimeout"`
+	Cookies    []fs.Cookie `json:"cookies"`
+	PostData   string      `json:"postData"`
+	Session    string      `json:"session"`
+}
+
+func (s *FlareSolverr) handle(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var body solverRequest
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		s.writeError(w, start, fmt.Sprintf("Error: Request body is not valid JSON: %v", err))
+		return
+	}
+	req := SolverRequest(body)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	var fault *SolverFault
+	switch req.Cmd {
+	case fs.CMD_REQUEST_GET, fs.CMD_REQUEST_POST:
+		if len(s.faults) > 0 {
+			fault = &s.faults[0]
+			s.faults = s.faults[1:]
+		}
+	case fs.CMD_SESSIONS_CREATE:
+		if !slices.Contains(s.sessions, req.Session) {
+			s.sessions = append(s.sessions, req.Session)
+		}
+	case fs.CMD_SESSIONS_DESTROY:
+		s.sessions = slices.DeleteFunc(s.sessions, func(id string) bool { return id == req.Session })
+	}
+	sessions := slices.Clone(s.sessions)
+	s.mu.Unlock()
+
+	switch req.Cmd {
+	case fs.CMD_REQUEST_GET, fs.CMD_REQUEST_POST:
+	case fs.CMD_SESSIONS_LIST:
+		s.writeOk(w, start, fs.Response{Sessions: sessions})
+		return
+	case fs.CMD_SESSIONS_CREATE:
+		s.writeOk(w, start, fs.Response{Session: req.Session})
+		return
+	case fs.CMD_SESSIONS_DESTROY:
+		s.writeOk(w, start, fs.Response{})
+		return
+	default:
+		s.writeError(w, start, fmt.Sprintf("Error: Request parameter 'cmd' = '%s' is invalid.", req.Cmd))
+		return
+	}
+	if req.Url == "" {
+		s.writeError(w, start, "Error: Request parameter 'url' is mandatory in 'request.get' command.")
+		return
+	}
+
+	if !s.wait(r.Context(), req.MaxTimeout) {
+		s.writeError(w, start, fmt.Sprintf("Error: Error solving the challenge. Timeout after %.1f seconds.",
+			float64(req.MaxTimeout)/1000))
+		return
+	}
+	if fault != nil {
+		if fault.Status == 0 {
+			s.writeError(w, start, fault.Message)
+			return
+		}
+		s.writeOk(w, start, fs.Response{Solution: &fs.Solution{
+			Url:       req.Url,
+			Status:    fault.Status,
+			UserAgent: s.UserAgent,
+			Response:  fault.Message,
+		}})
+		return
+	}
+
+	solution, err := s.solve(r.Context(), req)
+	if err != nil {
+		s.writeError(w, start, fmt.Sprintf("Error: Error solving the challenge. %v", err))
+		return
+	}
+	s.writeOk(w, start, fs.Response{Solution: solution})
+}
+
+// wait 等待 Delay, 超过 maxTimeout (毫秒, <= 0 不限制) 或请求取消时返回 false
+func (s *FlareSolverr) wait(ctx context.Context, maxTimeout int) bool {
+	if s.Delay <= 0 {
+		return true
+	}
+	d, timeout := s.Delay, false
+	if limit := time.Duration(maxTimeout) * time.Millisecond; maxTimeout > 0 && d > limit {
+		d, timeout = limit, true
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return !timeout
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// solve 带上请求的 cookies, Cookies 与配对的 clearance 访问目标链接
+func (s *FlareSolverr) solve(ctx context.Context, req SolverRequest) (*fs.Solution, error) {
+	u, err := url.Parse(req.Url)
+	if err != nil {
+		return nil, err
+	}
+	cookies := slices.Concat(req.Cookies, s.Cookies)
+	if s.Target != nil {
+		if v := s.Target.Clearance(); v != "" {
+			cookies = append(cookies, fs.Cookie{
+				Name:     CLEARANCE_COOKIE,
+				Value:    v,
+				Path:     "/",
+				Domain:   u.Hostname(),
+				Expiry:   time.Now().Add(time.Hour).Unix(),
+				HttpOnly: true,
+				SameSite: "None",
+			})
+		}
+	}
+
+	method, body := http.MethodGet, io.Reader(nil)
+	if req.Cmd == fs.CMD_REQUEST_POST {
+		method, body = http.MethodPost, strings.NewReader(req.PostData)
+	}
+	hReq, err := http.NewRequestWithContext(ctx, method, req.Url, body)
+	if err != nil {
+		return nil, err
+	}
+	if method == http.MethodPost {
+		hReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	hReq.Header.Set("User-Agent", s.UserAgent)
+	for _, c := range cookies {
+		hReq.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value})
+	}
+	hResp, err := http.DefaultClient.Do(hReq)
+	if err != nil {
+		return nil, err
+	}
+	defer hResp.Body.Close()
+	data, err := io.ReadAll(hResp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range hResp.Cookies() {
+		cookies = append(cookies, fs.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   u.Hostname(),
+			Expiry:   c.Expires.Unix(),
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		})
+	}
+	headers := make(map[string]string, len(hResp.Header))
+	for k := range hResp.Header {
+		headers[strings.ToLower(k)] = hResp.Header.Get(k)
+	}
+	return &fs.Solution{
+		Url:       hResp.Request.URL.String(),
+		Status:    hResp.StatusCode,
+		Cookies:   cookies,
+		UserAgent: s.UserAgent,
+		Headers:   headers,
+		Response:  string(data),
+	}, nil
+}
+
+func (s *FlareSolverr) writeOk(w http.ResponseWriter, start time.Time, resp fs.Response) {
+	resp.Status = fs.RESP_STATUS_OK
+	if resp.Solution != nil {
+		resp.Message = "Challenge solved!"
+	}
+	s.stamp(&resp.RespBase, start)
+	writeJson(w, http.StatusOK, resp)
+}
+
+// writeError 与 FlareSolverr 一致, 失败时 http 状态码为 500
+func (s *FlareSolverr) writeError(w http.ResponseWriter, start time.Time, message string) {
+	resp := fs.Response{RespBase: fs.RespBase{Status: "error", Message: message}}
+	s.stamp(&resp.RespBase, start)
+	writeJson(w, http.StatusInternalServerError, resp)
+}
+
+func (s *FlareSolverr) stamp(b *fs.RespBase, start time.Time) {
+	b.StartTimestamp = start.UnixMilli()
+	b.EndTimestamp = time.Now().UnixMilli()
+	b.Version = SOLVER_VERSION
+}
@@ -0,0 +1,180 @@
+package saucenaotest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	fs "github.com/Miuzarte/FlareSolverr-go"
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+)
+
+func TestFlareSolverrBypass(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	solver := NewFlareSolverr(srv)
+	defer solver.Close()
+	ctx := t.Context()
+
+	// 未配置 FlareSolverr 时直接返回 403
+	_, err := srv.Client().Get(ctx, "https://example.com/a.png")
+	var he *SauceNao.HttpError
+	if !errors.As(err, &he) || he.StatusCode != http.StatusForbidden {
+		t.Fatalf("without solver: %v", err)
+	}
+
+	c := srv.Client()
+	c.FlareSolverrClient = solver.Client()
+	var challenges, retries int
+	var solved []SauceNao.ChallengeSolvedEvent
+	c.Hooks = SauceNao.Hooks{
+		OnChallenge:       func(SauceNao.ChallengeEvent) { challenges++ },
+		OnChallengeSolved: func(ev SauceNao.ChallengeSolvedEvent) { solved = append(solved, ev) },
+		OnRetry:           func(SauceNao.RetryEvent) { retries++ },
+	}
+
+	resp, err := c.Get(ctx, "https://example.com/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) == 0 || challenges != 1 || retries != 1 || len(solved) != 1 || solved[0].Err != nil {
+		t.Fatalf("challenges %d, retries %d, solved %+v", challenges, retries, solved)
+	}
+	if solved[0].UserAgent != USER_AGENT {
+		t.Errorf("user agent = %q", solved[0].UserAgent)
+	}
+	req := srv.LastRequest()
+	if req.Header.Get("User-Agent") != USER_AGENT || !hasCookie(req.Header, CLEARANCE_COOKIE, CLEARANCE) {
+		t.Errorf("retried request header = %v", req.Header)
+	}
+	// 被 cf 拦下的请求不消耗限额
+	if resp.Header.ShortRemaining != 3 {
+		t.Errorf("short remaining = %d", resp.Header.ShortRemaining)
+	}
+
+	// 之后的请求直接带上 clearance
+	_, err = c.Get(ctx, "https://example.com/b.png")
+	if err != nil || challenges != 1 {
+		t.Errorf("second search: challenges %d, %v", challenges, err)
+	}
+	if n := len(solver.Requests()); n != 1 {
+		t.Errorf("solver received %d requests", n)
+	}
+
+	// 经 FlareSolverr 访问 user.php 不需要 clearance 以外的准备
+	account, err := c.Account(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Username != USERNAME || account.ApiKey != API_KEY || account.ShortUsage != 2 || account.LongLimit != 100 {
+		t.Errorf("account = %+v", account)
+	}
+}
+
+func TestFlareSolverrFailure(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	solver := NewFlareSolverr(srv)
+	defer solver.Close()
+	ctx := t.Context()
+
+	c := srv.Client()
+	c.FlareSolverrClient = solver.Client()
+	var solved []SauceNao.ChallengeSolvedEvent
+	c.Hooks.OnChallengeSolved = func(ev SauceNao.ChallengeSolvedEvent) { solved = append(solved, ev) }
+
+	solver.Inject(SolverFault{Message: "Error: Error solving the challenge."}, SolverFault{Status: http.StatusForbidden, Message: CF_CHALLENGE_PAGE})
+	for i := range 2 {
+		_, err := c.Get(ctx, "https://example.com/a.png")
+		var he *SauceNao.HttpError
+		if !errors.As(err, &he) || he.StatusCode != http.StatusForbidden {
+			t.Errorf("fault %d: %v", i, err)
+		}
+		if len(solved) != i+1 || solved[i].Err == nil {
+			t.Errorf("fault %d: solved %+v", i, solved)
+		}
+	}
+	if n := len(srv.Requests()); n != 2 {
+		t.Errorf("failed bypass should not retry, got %d requests", n)
+	}
+
+	// 超过 maxTimeout
+	solver.Delay = 50 * time.Millisecond
+	_, err := solver.Client().Get(ctx, srv.URL+SauceNao.USER_PATH, map[string]any{fs.PARAM_MAX_TIMEOUT: 10})
+	if err == nil {
+		t.Error("expected timeout")
+	}
+	solver.Delay = 0
+
+	// 恢复后正常
+	if _, err := c.Get(ctx, "https://example.com/a.png"); err != nil {
+		t.Error(err)
+	}
+}
+
+func hasCookie(h http.Header, name, value string) bool {
+	r := http.Request{Header: h}
+	c, err := r.Cookie(name)
+	return err == nil && c.Value == value
+}
+
+// 公开搜索页的 POST 先经 FlareSolverr 取得 clearance, 之后 clearance 失效时重新获取并重试
+func TestFlareSolverrHtmlPost(t *testing.T) {
+	page, err := os.ReadFile("../testdata/search.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clearance := "a"
+	var posts []*http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, _ := r.Cookie(CLEARANCE_COOKIE)
+		if cookie == nil || cookie.Value != clearance || r.UserAgent() != USER_AGENT {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, CF_CHALLENGE_PAGE)
+			return
+		}
+		if r.URL.Path != SauceNao.API_PATH {
+			fmt.Fprint(w, "ok")
+			return
+		}
+		posts = append(posts, r)
+		if r.Method != http.MethodPost || r.URL.Query().Get("output_type") != "0" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.Write(page)
+	}))
+	defer srv.Close()
+	solver := NewUnstartedFlareSolverr(nil)
+	solver.Cookies = []fs.Cookie{{Name: CLEARANCE_COOKIE, Value: clearance}}
+	solver.Start()
+	defer solver.Close()
+
+	c := SauceNao.NewClient("", srv.URL, 0, SauceNao.HIDE_NONE, nil)
+	c.HtmlFallback = true
+	c.FlareSolverrClient = solver.Client()
+	var challenges int
+	c.Hooks.OnChallenge = func(SauceNao.ChallengeEvent) { challenges++ }
+
+	resp, err := c.Post(t.Context(), []byte("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) == 0 || len(posts) != 1 || challenges != 0 || len(solver.Requests()) != 1 {
+		t.Fatalf("results %d, posts %d, challenges %d, solver requests %d", len(resp.Results), len(posts), challenges, len(solver.Requests()))
+	}
+
+	clearance = "b"
+	solver.Cookies = []fs.Cookie{{Name: CLEARANCE_COOKIE, Value: clearance}}
+	_, err = c.Post(t.Context(), []byte("b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 2 || challenges != 1 || len(solver.Requests()) != 2 {
+		t.Errorf("posts %d, challenges %d, solver requests %d", len(posts), challenges, len(solver.Requests()))
+	}
+}
@@ -0,0 +1,387 @@
+// Package saucenaotest 提供用于测试的假 SauceNAO 服务器, 实现 /search.php 的 GET 与 POST (output_type=2):
+// 按索引返回示例结果, 模拟限额递减与 429, 可按需返回负数 status, cf 的 403 页面, 并记录收到的参数.
+// 另有说 FlareSolverr /v1 协议的 [FlareSolverr], 与服务器配对后可在本地完整测试过 cf 后重试的流程
+//
+//	srv := saucenaotest.NewServer()
+//	defer srv.Close()
+//	client := srv.Client()
+package saucenaotest
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+const (
+	API_KEY            = "saucenaotest-key"
+	MINIMUM_SIMILARITY = 55.0
+	USERNAME           = "saucenaotest"
+)
+
+// CLEARANCE_COOKIE 过 cf 后获得的 cookie 名, 见 [Server.SetClearance]
+const CLEARANCE_COOKIE = "cf_clearance"
+
+// CF_CHALLENGE_PAGE 403 时返回的页面, 与 cf 的 "Just a moment..." 页面相似
+const CF_CHALLENGE_PAGE = `<!DOCTYPE html><html lang="en-US"><head><title>Just a moment...</title></head>` +
+	`<body><div id="challenge-running">Checking if the site connection is secure</div>` +
+	`<script src="/cdn-cgi/challenge-platform/h/b/orchestrate/chl_page/v1"></script></body></html>`
+
+// USER_PAGE 与 testdata/user.html 相同的页面, 参数依次为用户名, api key, 30s 上限, 30s 已用, 24h 上限, 24h 已用
+const USER_PAGE = `<!DOCTYPE html>
+<html>
+<head><title>SauceNAO - User Account</title></head>
+<body>
+<div id="mainarea">
+<div id="middle">
+<h3>Account Info</h3>
+<table class="accountinfo">
+<tr><td>Username:</td><td>%s</td></tr>
+<tr><td>Account Type:</td><td>Basic</td></tr>
+</table>
+<h3>Search API</h3>
+<p>api key: <input type="text" readonly="readonly" size="42" value="%s" /></p>
+<table class="limits">
+<tr><td>30 Second Limit:</td><td>%d</td></tr>
+<tr><td>30 Second Usage:</td><td>%d</td></tr>
+<tr><td>Daily Limit:</td><td>%d</td></tr>
+<tr><td>Daily Usage:</td><td>%d</td></tr>
+</table>
+</div>
+</div>
+</body>
+</html>
+`
+
+type Server struct {
+	*httptest.Server
+
+	ShortLimit  int           // 默认 4, 启动后修改需调用 [Server.ResetQuota]
+	LongLimit   int           // 默认 100, 同上
+	ShortWindow time.Duration // 默认 [SauceNao.SHORT_WINDOW]
+	ApiKey      string        // 非空时校验 api_key, 默认 [API_KEY]
+
+	mu             sync.Mutex
+	results        []SauceNao.Result
+	shortRemaining int
+	longRemaining  int
+	windowStart    time.Time
+	faults         []Fault
+	requests       []Request
+	clearance      string
+}
+
+// Request 收到的一次搜索请求
+type Request struct {
+	Method string
+	Params url.Values // query 与表单参数
+	File   []byte     // POST 上传的图片
+	Header http.Header
+}
+
+// Fault 下一次请求返回的异常响应, 见 [Server.Inject]
+type Fault struct {
+	StatusCode int    // http 状态码, 0 为 200
+	Status     int    // json header.status, 非 0 时返回没有结果的响应
+	Message    string // json header.message
+	Body       string // 非空时原样返回, 忽略 Status 与 Message
+}
+
+// FaultChallenge cf challenge 的 403 页面
+func FaultChallenge() Fault {
+	return Fault{StatusCode: http.StatusForbidden, Body: CF_CHALLENGE_PAGE}
+}
+
+// FaultShortLimit 30s 限额用尽
+func FaultShortLimit() Fault {
+	return Fault{StatusCode: http.StatusTooManyRequests, Status: -2, Message: "Search Rate Too High. Your IP has exceeded the basic account type's rate limit of 4 searches every 30 seconds."}
+}
+
+// FaultLongLimit 24h 限额用尽
+func FaultLongLimit() Fault {
+	return Fault{StatusCode: http.StatusTooManyRequests, Status: -2, Message: "Daily Search Limit Exceeded. Your IP has exceeded the basic account type's daily limit of 100 searches."}
+}
+
+// FaultStatus http 200, header.status 为负数表示客户端侧错误 (如图片无法识别), 正数为服务端错误
+func FaultStatus(status int, message string) Fault {
+	return Fault{Status: status, Message: message}
+}
+
+// NewServer 启动并加载 [DefaultFixtures]
+func NewServer() *Server {
+	s := NewUnstartedServer()
+	s.Start()
+	return s
+}
+
+// NewUnstartedServer 可在启动前修改字段
+func NewUnstartedServer() *Server {
+	s := &Server{
+		ShortLimit:  4,
+		LongLimit:   100,
+		ShortWindow: SauceNao.SHORT_WINDOW,
+		ApiKey:      API_KEY,
+		results:     DefaultFixtures(),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(SauceNao.API_PATH, s.handleSearch)
+	mux.HandleFunc(SauceNao.USER_PATH, s.handleUser)
+	s.Server = httptest.NewUnstartedServer(mux)
+	return s
+}
+
+// Start 同时重置限额
+func (s *Server) Start() {
+	s.ResetQuota()
+	s.Server.Start()
+}
+
+// Client 返回指向此服务器的 client
+func (s *Server) Client() *SauceNao.Client {
+	return SauceNao.NewClient(s.ApiKey, s.URL, 0, SauceNao.HIDE_NONE, nil)
+}
+
+// SetResults 替换返回的结果, 响应时按相似度降序并按 numres, dbmask 筛选
+func (s *Server) SetResults(results ...SauceNao.Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = slices.Clone(results)
+}
+
+// ResetQuota 恢复为 ShortLimit 与 LongLimit
+func (s *Server) ResetQuota() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shortRemaining, s.longRemaining = s.ShortLimit, s.LongLimit
+	s.windowStart = time.Now()
+}
+
+// SetRemaining 直接设置剩余次数
+func (s *Server) SetRemaining(short, long int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shortRemaining, s.longRemaining = short, long
+	s.windowStart = time.Now()
+}
+
+// Inject 之后的请求依次返回这些异常响应, 用完后恢复正常
+func (s *Server) Inject(faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, faults...)
+}
+
+// SetClearance 非空时, 没有带上值相同的 [CLEARANCE_COOKIE] 的请求 (含 user.php) 均返回 cf 的 403 页面,
+// 空字符串取消. 与 [FlareSolverr] 配对时自动设置
+func (s *Server) SetClearance(value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clearance = value
+}
+
+// Clearance 返回当前要求的 [CLEARANCE_COOKIE] 值
+func (s *Server) Clearance() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clearance
+}
+
+// cleared 调用方需持有 mu
+func (s *Server) cleared(r *http.Request) bool {
+	if s.clearance == "" {
+		return true
+	}
+	c, err := r.Cookie(CLEARANCE_COOKIE)
+	return err == nil && c.Value == s.clearance
+}
+
+// Requests 返回收到的所有搜索请求
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// LastRequest 没有请求时返回零值
+func (s *Server) LastRequest() Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) == 0 {
+		return Request{}
+	}
+	return s.requests[len(s.requests)-1]
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	req := Request{Method: r.Method, Header: r.Header.Clone()}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		err := r.ParseMultipartForm(32 << 20)
+		if err != nil && err != http.ErrNotMultipart {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if f, _, err := r.FormFile("file"); err == nil {
+			req.File, _ = io.ReadAll(f)
+			f.Close()
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseForm()
+	req.Params = r.Form
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+
+	if !s.cleared(r) {
+		s.writeFault(w, FaultChallenge())
+		return
+	}
+	if len(s.faults) > 0 {
+		f := s.faults[0]
+		s.faults = s.faults[1:]
+		s.writeFault(w, f)
+		return
+	}
+	if s.ApiKey != "" && req.Params.Get("api_key") != s.ApiKey {
+		s.writeFault(w, Fault{StatusCode: http.StatusForbidden, Status: -1, Message: "Invalid API key."})
+		return
+	}
+	if req.Params.Get("output_type") != "2" {
+		s.writeFault(w, Fault{StatusCode: http.StatusNotImplemented, Body: "saucenaotest: only output_type=2 is supported"})
+		return
+	}
+	if req.File == nil && req.Params.Get("url") == "" {
+		s.writeFault(w, FaultStatus(-3, "No image specified."))
+		return
+	}
+
+	if time.Since(s.windowStart) >= s.ShortWindow {
+		s.shortRemaining = s.ShortLimit
+		s.windowStart = time.Now()
+	}
+	switch {
+	case s.longRemaining <= 0:
+		s.writeFault(w, FaultLongLimit())
+		return
+	case s.shortRemaining <= 0:
+		s.writeFault(w, FaultShortLimit())
+		return
+	}
+	s.shortRemaining--
+	s.longRemaining--
+
+	results := s.selectResults(req.Params)
+	header := s.header()
+	header["status"] = 0
+	header["results_requested"], _ = strconv.Atoi(req.Params.Get("numres"))
+	header["results_returned"] = len(results)
+	header["query_image"] = "fixture.jpg"
+	if u := req.Params.Get("url"); u != "" {
+		header["query_image_display"] = u
+	}
+	writeJson(w, http.StatusOK, map[string]any{"header": header, "results": rawResults(results)})
+}
+
+// handleUser 已登录的 user.php, 用量与当前限额一致
+func (s *Server) handleUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.cleared(r) {
+		s.writeFault(w, FaultChallenge())
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	fmt.Fprintf(w, USER_PAGE, USERNAME, s.ApiKey,
+		s.ShortLimit, s.ShortLimit-s.shortRemaining, s.LongLimit, s.LongLimit-s.longRemaining)
+}
+
+func (s *Server) header() map[string]any {
+	return map[string]any{
+		"user_id":            "0",
+		"account_type":       "1",
+		"short_limit":        strconv.Itoa(s.ShortLimit),
+		"long_limit":         strconv.Itoa(s.LongLimit),
+		"short_remaining":    s.shortRemaining,
+		"long_remaining":     s.longRemaining,
+		"search_depth":       "128",
+		"minimum_similarity": MINIMUM_SIMILARITY,
+	}
+}
+
+// selectResults 按 dbmask (或 db) 与 numres 筛选, 相似度降序
+func (s *Server) selectResults(params url.Values) []SauceNao.Result {
+	var mask uint64
+	if v := params.Get("dbmask"); v != "" {
+		mask, _ = strconv.ParseUint(v, 10, 64)
+	} else if v := params.Get("db"); v != "" && v != "999" {
+		id, _ := strconv.Atoi(v)
+		mask = db.Mask(db.IndexId(id))
+	}
+	results := []SauceNao.Result{}
+	for _, r := range s.results {
+		if mask == 0 || mask&db.Mask(r.Header.IndexId) != 0 {
+			results = append(results, r)
+		}
+	}
+	slices.SortStableFunc(results, func(a, b SauceNao.Result) int {
+		return cmp.Compare(b.Header.SimilarityFloat(), a.Header.SimilarityFloat())
+	})
+	if n, _ := strconv.Atoi(params.Get("numres")); n > 0 && n < len(results) {
+		results = results[:n]
+	}
+	return results
+}
+
+// rawResults 按 api 的格式输出, 不使用 [SauceNao.Result.MarshalJSON] 的存储格式
+func rawResults(results []SauceNao.Result) []map[string]any {
+	raw := make([]map[string]any, 0, len(results))
+	for _, r := range results {
+		raw = append(raw, map[string]any{"header": r.Header, "data": r.Data})
+	}
+	return raw
+}
+
+func (s *Server) writeFault(w http.ResponseWriter, f Fault) {
+	code := f.StatusCode
+	if code == 0 {
+		code = http.StatusOK
+	}
+	if f.Body != "" {
+		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+		w.WriteHeader(code)
+		io.WriteString(w, f.Body)
+		return
+	}
+	header := s.header()
+	header["status"] = f.Status
+	header["message"] = f.Message
+	header["results_returned"] = 0
+	writeJson(w, code, map[string]any{"header": header})
+}
+
+func writeJson(w http.ResponseWriter, code int, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("saucenaotest: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
@@ -0,0 +1,80 @@
+package saucenaotest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+func TestServer(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	ctx := t.Context()
+
+	resp, err := c.Search(ctx, []byte("image"), SauceNao.WithNumRes(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 3 || resp.Results[0].Header.IndexId != db.PIXIV || resp.Header.ShortRemaining != 3 {
+		t.Fatalf("resp = %+v", resp.Header)
+	}
+	req := srv.LastRequest()
+	if req.Method != http.MethodPost || string(req.File) != "image" || req.Params.Get("numres") != "3" {
+		t.Errorf("request = %+v", req)
+	}
+
+	resp, err = c.Get(ctx, "https://example.com/a.png", SauceNao.WithDbMask(db.Mask(db.DANBOORU, db.ANIME)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 2 || resp.Results[0].Header.IndexId != db.DANBOORU || resp.Header.LongRemaining != 98 {
+		t.Errorf("resp = %+v", resp)
+	}
+	if got := resp.Confidence(resp.Results[1], nil); got != SauceNao.CONFIDENCE_BELOW_MINIMUM {
+		t.Errorf("anime fixture confidence = %v", got)
+	}
+
+	srv.Inject(FaultStatus(-3, "unreadable"), FaultChallenge())
+	resp, err = c.Post(ctx, []byte("x"))
+	if err != nil || resp.Header.Status != -3 || len(resp.Results) != 0 {
+		t.Errorf("status fault: %+v, %v", resp, err)
+	}
+	_, err = c.Post(ctx, []byte("x"))
+	var he *SauceNao.HttpError
+	if !errors.As(err, &he) || he.StatusCode != http.StatusForbidden {
+		t.Errorf("challenge fault: %v", err)
+	}
+
+	// 30s 限额在第 4 次成功搜索后用尽
+	c.Post(ctx, []byte("x"))
+	c.Post(ctx, []byte("x"))
+	_, err = c.Post(ctx, []byte("x"))
+	if !SauceNao.IsRateLimited(err) {
+		t.Errorf("short limit: %v", err)
+	}
+
+	srv.SetRemaining(4, 0)
+	_, err = c.Post(ctx, []byte("x"))
+	if !SauceNao.IsRateLimited(err) || c.WaitQuota(ctx) != SauceNao.ErrLongLimit {
+		t.Errorf("long limit: %v", err)
+	}
+	if n := len(srv.Requests()); n != 8 {
+		t.Errorf("recorded %d requests", n)
+	}
+}
+
+func TestUserPage(t *testing.T) {
+	want, err := os.ReadFile("../testdata/user.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprintf(USER_PAGE, "some_user", "0123456789abcdef0123456789abcdef01234567", 4, 1, 1000, 15); got != string(want) {
+		t.Errorf("USER_PAGE differs from testdata/user.html:\n%s", got)
+	}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+)
+
+// Caller 一个调用方, 以 "Authorization: Bearer {Token}" 认证
+type Caller struct {
+	Name       string  `json:"name"`
+	Token      string  `json:"token"`
+	Weight     int     `json:"weight"`      // 排队时的权重, 默认 1
+	DailyShare float64 `json:"daily_share"` // 每日可用次数占 LongLimit 的比例, 0 为不限制
+	DailyLimit int     `json:"daily_limit"` // 每日可用次数, 非 0 时优先于 DailyShare
+	BatchOnly  bool    `json:"batch_only"`  // 请求一律视为 [PRIORITY_BATCH]
+}
+
+// Usage 调用方在当前 24h 窗口内的用量
+type Usage struct {
+	Caller  string    `json:"caller"`
+	Used    int       `json:"used"`
+	Limit   int       `json:"limit"` // 0 为不限制
+	ResetAt time.Time `json:"reset_at,omitzero"`
+}
+
+// anonymous 未配置 [Server.Callers] 时所有请求视为同一调用方
+var anonymous = &Caller{}
+
+type usageCounter struct {
+	used  int
+	start time.Time
+}
+
+// usageTable 各调用方的用量, 窗口自首次使用起 24h
+type usageTable struct {
+	mu     sync.Mutex
+	counts map[string]*usageCounter
+}
+
+// dailyLimit 根据最近得知的 LongLimit 计算, 尚未得知时不限制
+func dailyLimit(c *Caller, q SauceNao.Quota) int {
+	if c.DailyLimit > 0 {
+		return c.DailyLimit
+	}
+	if c.DailyShare > 0 && q.LongLimit > 0 {
+		return max(1, int(c.DailyShare*float64(q.LongLimit)))
+	}
+	return 0
+}
+
+func (ut *usageTable) counter(name string, now time.Time) *usageCounter {
+	if ut.counts == nil {
+		ut.counts = map[string]*usageCounter{}
+	}
+	uc := ut.counts[name]
+	if uc == nil || now.Sub(uc.start) >= SauceNao.LONG_WINDOW {
+		uc = &usageCounter{start: now}
+		ut.counts[name] = uc
+	}
+	return uc
+}
+
+// reserve 占用一次, 超出时返回 false 与窗口重置时间
+func (ut *usageTable) reserve(c *Caller, limit int) (bool, time.Time) {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+	uc := ut.counter(c.Name, time.Now())
+	if limit > 0 && uc.used >= limit {
+		return false, uc.start.Add(SauceNao.LONG_WINDOW)
+	}
+	uc.used++
+	return true, time.Time{}
+}
+
+// refund 请求未到达上游时退回
+func (ut *usageTable) refund(c *Caller) {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+	if uc := ut.counts[c.Name]; uc != nil && uc.used > 0 {
+		uc.used--
+	}
+}
+
+func (ut *usageTable) usage(c *Caller, limit int) Usage {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+	u := Usage{Caller: c.Name, Limit: limit}
+	if uc := ut.counts[c.Name]; uc != nil && time.Since(uc.start) < SauceNao.LONG_WINDOW {
+		u.Used = uc.used
+		u.ResetAt = uc.start.Add(SauceNao.LONG_WINDOW)
+	}
+	return u
+}
+
+// authenticate 未配置调用方时不认证
+func (s *Server) authenticate(r *http.Request) (*Caller, bool) {
+	if len(s.Callers) == 0 {
+		return anonymous, true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, false
+	}
+	for i := range s.Callers {
+		c := &s.Callers[i]
+		if subtle.ConstantTimeCompare([]byte(c.Token), []byte(token)) == 1 {
+			return c, true
+		}
+	}
+	return nil, false
+}
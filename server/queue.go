@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"sync"
+)
+
+type Priority int
+
+const (
+	PRIORITY_BATCH       Priority = iota // 批量任务, 仅在没有交互请求排队时处理
+	PRIORITY_INTERACTIVE                 // 默认
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PRIORITY_BATCH:
+		return "batch"
+	case PRIORITY_INTERACTIVE:
+		return "interactive"
+	}
+	return "unknown"
+}
+
+// ParsePriority 空字符串为 [PRIORITY_INTERACTIVE]
+func ParsePriority(s string) (Priority, bool) {
+	switch s {
+	case "", "interactive":
+		return PRIORITY_INTERACTIVE, true
+	case "batch":
+		return PRIORITY_BATCH, true
+	}
+	return 0, false
+}
+
+// queue 同一时间只放行一个请求, 排队的请求先按优先级,
+// 同优先级内按调用方的权重做加权公平排队 (按虚拟完成时间)
+type queue struct {
+	mu      sync.Mutex
+	busy    bool
+	seq     uint64
+	vtime   float64            // 最近放行的请求的虚拟完成时间
+	finish  map[string]float64 // 各调用方最后一个请求的虚拟完成时间
+	waiting []*ticket
+}
+
+type ticket struct {
+	caller   string
+	priority Priority
+	finish   float64
+	seq      uint64
+	ready    chan struct{}
+}
+
+func (t *ticket) before(o *ticket) bool {
+	if t.priority != o.priority {
+		return t.priority > o.priority
+	}
+	if t.finish != o.finish {
+		return t.finish < o.finish
+	}
+	return t.seq < o.seq
+}
+
+// acquire 等待轮到自己, 成功后须调用 release
+func (q *queue) acquire(ctx context.Context, caller string, weight int, priority Priority) error {
+	if weight <= 0 {
+		weight = 1
+	}
+	q.mu.Lock()
+	if q.finish == nil {
+		q.finish = map[string]float64{}
+	}
+	start := max(q.vtime, q.finish[caller])
+	t := &ticket{
+		caller:   caller,
+		priority: priority,
+		finish:   start + 1/float64(weight),
+		seq:      q.seq,
+		ready:    make(chan struct{}),
+	}
+	q.seq++
+	q.finish[caller] = t.finish
+	if !q.busy {
+		q.busy = true
+		q.vtime = t.finish
+		q.mu.Unlock()
+		return nil
+	}
+	q.waiting = append(q.waiting, t)
+	q.mu.Unlock()
+
+	select {
+	case <-t.ready:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		for i, w := range q.waiting {
+			if w == t {
+				q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+				q.mu.Unlock()
+				return ctx.Err()
+			}
+		}
+		q.mu.Unlock()
+		// 取消的同时已被放行, 让给下一个
+		q.release()
+		return ctx.Err()
+	}
+}
+
+// release 放行下一个请求
+func (q *queue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.waiting) == 0 {
+		q.busy = false
+		return
+	}
+	next := 0
+	for i, t := range q.waiting {
+		if t.before(q.waiting[next]) {
+			next = i
+		}
+	}
+	t := q.waiting[next]
+	q.waiting = append(q.waiting[:next], q.waiting[next+1:]...)
+	q.vtime = t.finish
+	close(t.ready)
+}
+
+// len 排队中的请求数
+func (q *queue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiting)
+}
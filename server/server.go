@@ -0,0 +1,341 @@
+// Package server 将一个共享的 [SauceNao.Client] 包装为本地 REST API,
+// 多个服务共用同一个 api key, cf 状态, 限额与缓存
+//
+//	POST /search  multipart 的 file 字段, 或 url 参数 / {"url": "..."}
+//	GET  /quota   最近一次得知的限额
+//	GET  /usage   调用方自身的每日用量
+//	GET  /healthz 存活检查
+//	GET  /readyz  当日次数用尽时返回 503
+//	GET  /metrics 设置了 [SauceNao.Client.Metrics] 时提供, 不需要认证
+//
+// 配置 [Server.Callers] 后除健康检查外都需要 "Authorization: Bearer {token}",
+// 请求按调用方的权重公平排队, 并受各自的每日次数限制.
+// 批量任务可以 "X-Priority: batch" 或 ?priority=batch 让交互请求优先
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+// DEFAULT_MAX_UPLOAD 与 saucenao 的上传限制一致
+const DEFAULT_MAX_UPLOAD = 20 << 20
+
+type Server struct {
+	Client    *SauceNao.Client
+	MaxUpload int64    // 上传文件的最大字节数, 默认 [DEFAULT_MAX_UPLOAD]
+	Callers   []Caller // 为空时不认证, 所有请求共用一个队列且没有单独的每日限制
+
+	once  sync.Once
+	mux   *http.ServeMux
+	queue queue // 同一时间只向上游发送一个请求, 保证 WaitQuota 的判断有效
+	usage usageTable
+}
+
+type SearchResult struct {
+	Similarity float64         `json:"similarity"`
+	Confidence string          `json:"confidence"`
+	IndexId    db.IndexId      `json:"index_id"`
+	IndexName  string          `json:"index_name"`
+	Thumbnail  string          `json:"thumbnail"`
+	Hidden     bool            `json:"hidden"`
+	Urls       []string        `json:"urls"`
+	Data       json.RawMessage `json:"data"`
+}
+
+type SearchResponse struct {
+	MinimumSimilarity float64        `json:"minimum_similarity"`
+	Results           []SearchResult `json:"results"`
+	Quota             SauceNao.Quota `json:"quota"`
+}
+
+var ErrCallerLimit = errors.New("caller daily limit reached")
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) init() {
+	s.once.Do(func() {
+		if s.MaxUpload <= 0 {
+			s.MaxUpload = DEFAULT_MAX_UPLOAD
+		}
+		s.mux = http.NewServeMux()
+		s.mux.HandleFunc("POST /search", s.auth(s.handleSearch))
+		s.mux.HandleFunc("GET /quota", s.auth(s.handleQuota))
+		s.mux.HandleFunc("GET /usage", s.auth(s.handleUsage))
+		s.mux.HandleFunc("GET /healthz", s.handleHealth)
+		s.mux.HandleFunc("GET /readyz", s.handleReady)
+		if s.Client.Metrics != nil {
+			s.mux.Handle("GET /metrics", s.Client.Metrics)
+		}
+	})
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.init()
+	s.mux.ServeHTTP(w, r)
+}
+
+type callerKey struct{}
+
+// auth 认证失败时返回 401, 成功时将 [Caller] 放入 context
+func (s *Server) auth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := s.authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("invalid or missing token"))
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), callerKey{}, caller)))
+	}
+}
+
+func callerOf(r *http.Request) *Caller {
+	if c, ok := r.Context().Value(callerKey{}).(*Caller); ok {
+		return c
+	}
+	return anonymous
+}
+
+func writeJson(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJson(w, status, ErrorResponse{Error: err.Error()})
+}
+
+// searchInput 返回上传的图片或图片链接, 二者之一
+func (s *Server) searchInput(r *http.Request) (image any, err error) {
+	r.Body = http.MaxBytesReader(nil, r.Body, s.MaxUpload+1<<20)
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch ct {
+	case "multipart/form-data":
+		err = r.ParseMultipartForm(s.MaxUpload)
+		if err != nil {
+			return nil, err
+		}
+		if f, _, err := r.FormFile("file"); err == nil {
+			defer f.Close()
+			data, err := io.ReadAll(f)
+			if err != nil {
+				return nil, err
+			}
+			return data, nil
+		}
+	case "application/json":
+		var body struct {
+			Url string `json:"url"`
+		}
+		err = json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			return nil, err
+		}
+		if body.Url != "" {
+			return body.Url, nil
+		}
+	}
+	if u := r.FormValue("url"); u != "" {
+		return u, nil
+	}
+	return nil, errors.New("missing file or url")
+}
+
+// searchOptions 可选的查询参数 numres, dbmask, hide, safe
+func searchOptions(r *http.Request) ([]SauceNao.Option, error) {
+	var opts []SauceNao.Option
+	q := r.URL.Query()
+	if v := q.Get("numres"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("numres: %w", err)
+		}
+		opts = append(opts, SauceNao.WithNumRes(n))
+	}
+	if v := q.Get("dbmask"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("dbmask: %w", err)
+		}
+		opts = append(opts, SauceNao.WithDbMask(n))
+	}
+	if v := q.Get("hide"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < int(SauceNao.HIDE_NONE) || n > int(SauceNao.HIDE_ALL_BUT_SAFE) {
+			return nil, fmt.Errorf("hide: invalid level %q", v)
+		}
+		opts = append(opts, SauceNao.WithHide(SauceNao.HideLevel(n)))
+	}
+	if v := q.Get("safe"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("safe: %w", err)
+		}
+		opts = append(opts, SauceNao.WithSafeFilter(b))
+	}
+	return opts, nil
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	image, err := s.searchInput(r)
+	if err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			writeError(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if u, ok := image.(string); ok && !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		writeError(w, http.StatusBadRequest, errors.New("url must be http(s)"))
+		return
+	}
+	opts, err := searchOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	priority, ok := ParsePriority(r.Header.Get("X-Priority"))
+	if v := r.URL.Query().Get("priority"); ok && v != "" {
+		priority, ok = ParsePriority(v)
+	}
+	if !ok {
+		writeError(w, http.StatusBadRequest, errors.New("priority must be interactive or batch"))
+		return
+	}
+	caller := callerOf(r)
+	if caller.BatchOnly {
+		priority = PRIORITY_BATCH
+	}
+
+	// 命中缓存不消耗上游次数, 不计入用量也不排队
+	if resp, ok := s.Client.CachedSearch(image, opts...); ok {
+		writeJson(w, http.StatusOK, s.searchResponse(resp))
+		return
+	}
+
+	reserved, resetAt := s.usage.reserve(caller, dailyLimit(caller, s.Client.Quota()))
+	if !reserved {
+		setRetryAfter(w, time.Until(resetAt))
+		writeError(w, http.StatusTooManyRequests, ErrCallerLimit)
+		return
+	}
+
+	ctx := r.Context()
+	err = s.queue.acquire(ctx, caller.Name, caller.Weight, priority)
+	if err != nil {
+		s.usage.refund(caller)
+		return
+	}
+	resp, cached, err := s.search(r, image, opts)
+	s.queue.release()
+	if err != nil || cached {
+		// 失败与排队期间被其他请求写入缓存的都不计
+		s.usage.refund(caller)
+	}
+
+	switch {
+	case errors.Is(err, SauceNao.ErrLongLimit), SauceNao.IsRateLimited(err):
+		s.setQuotaRetryAfter(w)
+		writeError(w, http.StatusTooManyRequests, err)
+		return
+	case err != nil && ctx.Err() != nil:
+		return
+	case err != nil:
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJson(w, http.StatusOK, s.searchResponse(resp))
+}
+
+// search 返回的 cached 为 true 时结果来自缓存, 没有请求上游
+func (s *Server) search(r *http.Request, image any, opts []SauceNao.Option) (resp *SauceNao.Response, cached bool, err error) {
+	if resp, ok := s.Client.CachedSearch(image, opts...); ok {
+		return resp, true, nil
+	}
+	err = s.Client.WaitQuota(r.Context())
+	if err != nil {
+		return nil, false, err
+	}
+	resp, err = s.Client.Search(r.Context(), image, opts...)
+	return resp, false, err
+}
+
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, int(wait.Seconds()+0.5))))
+}
+
+// setQuotaRetryAfter 根据限额推算还需等待的时间
+func (s *Server) setQuotaRetryAfter(w http.ResponseWriter) {
+	q := s.Client.Quota()
+	window := SauceNao.SHORT_WINDOW
+	if q.LongRemaining <= 0 {
+		window = SauceNao.LONG_WINDOW
+	}
+	setRetryAfter(w, time.Until(q.UpdatedAt.Add(window)))
+}
+
+func (s *Server) searchResponse(resp *SauceNao.Response) SearchResponse {
+	out := SearchResponse{
+		MinimumSimilarity: resp.Header.MinimumSimilarity,
+		Results:           make([]SearchResult, 0, len(resp.Results)),
+		Quota:             s.Client.Quota(),
+	}
+	for _, result := range resp.Results {
+		data := result.DecodeData()
+		var urls []string
+		for _, u := range data.CanonicalURLs() {
+			urls = append(urls, u.String())
+		}
+		out.Results = append(out.Results, SearchResult{
+			Similarity: result.Header.SimilarityFloat(),
+			Confidence: resp.Confidence(result, nil).String(),
+			IndexId:    result.Header.IndexId,
+			IndexName:  result.Header.IndexId.String(),
+			Thumbnail:  result.Header.Thumbnail,
+			Hidden:     result.Header.IsHidden(),
+			Urls:       urls,
+			Data:       json.RawMessage(data.Json("")),
+		})
+	}
+	return out
+}
+
+func (s *Server) handleQuota(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, s.Client.Quota())
+}
+
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	caller := callerOf(r)
+	writeJson(w, http.StatusOK, s.usage.usage(caller, dailyLimit(caller, s.Client.Quota())))
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	q := s.Client.Quota()
+	if q.Known() && q.LongRemaining <= 0 && time.Since(q.UpdatedAt) < SauceNao.LONG_WINDOW {
+		writeJson(w, http.StatusServiceUnavailable, map[string]string{"status": SauceNao.ErrLongLimit.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]string{"status": "ok"})
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+)
+
+func TestServer(t *testing.T) {
+	upstream, longRemaining := 0, 2
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstream++
+		if longRemaining <= 0 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"header":{"status":-2,"message":"Daily Search Limit Exceeded."}}`)
+			return
+		}
+		longRemaining--
+		fmt.Fprintf(w, `{"header":{"short_limit":"4","long_limit":"100","short_remaining":3,"long_remaining":%d,"minimum_similarity":50},
+"results":[{"header":{"similarity":"90.00","index_id":5},"data":{"pixiv_id":1,"member_id":2,"title":"t"}}]}`, longRemaining)
+	}))
+	defer api.Close()
+
+	client := SauceNao.NewClient("key", api.URL, 0, SauceNao.HIDE_NONE, nil)
+	client.Cache = SauceNao.NewMemoryCache(8, time.Minute)
+	srv := httptest.NewServer(&Server{Client: client})
+	defer srv.Close()
+
+	upload := func(data string) *http.Response {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", "a.png")
+		fw.Write([]byte(data))
+		mw.Close()
+		resp, err := http.Post(srv.URL+"/search", mw.FormDataContentType(), &body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// 第二次命中缓存, 不请求上游
+	for range 2 {
+		resp := upload("image")
+		var sr SearchResponse
+		json.NewDecoder(resp.Body).Decode(&sr)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || len(sr.Results) != 1 || sr.Results[0].Urls[0] != "https://www.pixiv.net/artworks/1" {
+			t.Fatalf("status %d, %+v", resp.StatusCode, sr)
+		}
+	}
+	if upstream != 1 {
+		t.Errorf("upstream requests = %d, want 1", upstream)
+	}
+
+	resp, _ := http.Post(srv.URL+"/search", "application/json", strings.NewReader(`{"url":"/etc/passwd"}`))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("local path: status %d", resp.StatusCode)
+	}
+	resp, _ = http.Post(srv.URL+"/search?url=https://example.com/a.png", "", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("url: status %d", resp.StatusCode)
+	}
+
+	resp = upload("other")
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("limit: status %d, Retry-After %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	resp, _ = http.Get(srv.URL + "/readyz")
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("readyz: status %d", resp.StatusCode)
+	}
+
+	resp, _ = http.Get(srv.URL + "/quota")
+	var q SauceNao.Quota
+	json.NewDecoder(resp.Body).Decode(&q)
+	if q.LongLimit != 100 || q.LongRemaining != 0 {
+		t.Errorf("quota = %+v", q)
+	}
+}
+
+func TestQueueOrder(t *testing.T) {
+	q := &queue{}
+	q.acquire(t.Context(), "holder", 1, PRIORITY_INTERACTIVE)
+
+	var order []string
+	done := make(chan struct{})
+	enqueue := func(caller string, weight int, p Priority) {
+		n := q.len()
+		go func() {
+			q.acquire(t.Context(), caller, weight, p)
+			order = append(order, caller)
+			q.release()
+			done <- struct{}{}
+		}()
+		// 等它进入队列, 保证入队顺序
+		for q.len() == n {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	// noisy 的 3 个批量请求排在前面, 后到的交互请求与高权重调用方仍应优先
+	enqueue("noisy", 1, PRIORITY_BATCH)
+	enqueue("noisy", 1, PRIORITY_BATCH)
+	enqueue("light", 1, PRIORITY_BATCH)
+	enqueue("user", 1, PRIORITY_INTERACTIVE)
+	q.release()
+	for range 4 {
+		<-done
+	}
+	want := "user,noisy,light,noisy"
+	if got := strings.Join(order, ","); got != want {
+		t.Errorf("order = %s, want %s", got, want)
+	}
+}
+
+func TestCallers(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"header":{"short_limit":"4","long_limit":"100","short_remaining":3,"long_remaining":90},"results":[]}`)
+	}))
+	defer api.Close()
+	srv := httptest.NewServer(&Server{
+		Client:  SauceNao.NewClient("key", api.URL, 0, SauceNao.HIDE_NONE, nil),
+		Callers: []Caller{{Name: "bot", Token: "secret", DailyShare: 0.02}},
+	})
+	defer srv.Close()
+
+	search := func(token string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/search?url=https://example.com/a.png", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := search("wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("wrong token: status %d", resp.StatusCode)
+	}
+	// LongLimit 100 的 2% 为 2 次
+	for i := range 2 {
+		if resp := search("secret"); resp.StatusCode != http.StatusOK {
+			t.Fatalf("search %d: status %d", i, resp.StatusCode)
+		}
+	}
+	resp := search("secret")
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("over limit: status %d, Retry-After %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+}
+
+// 命中缓存不计入用量, 也不等待正在请求上游的其他调用方
+func TestCacheHits(t *testing.T) {
+	block := make(chan struct{})
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.RawQuery, "slow") {
+			<-block
+		}
+		fmt.Fprint(w, `{"header":{"short_limit":"4","long_limit":"100","short_remaining":3,"long_remaining":90},"results":[]}`)
+	}))
+	defer api.Close()
+	client := SauceNao.NewClient("key", api.URL, 0, SauceNao.HIDE_NONE, nil)
+	client.Cache = SauceNao.NewMemoryCache(8, time.Minute)
+	srv := httptest.NewServer(&Server{
+		Client:  client,
+		Callers: []Caller{{Name: "bot", Token: "secret", DailyShare: 0.02}},
+	})
+	defer srv.Close()
+	defer close(block)
+
+	search := func(imgUrl string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/search?url="+imgUrl, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error(err)
+			return nil
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// LongLimit 100 的 2% 为 2 次, 命中缓存的不计
+	for i := range 3 {
+		if resp := search("https://example.com/a.png"); resp.StatusCode != http.StatusOK {
+			t.Fatalf("search %d: status %d", i, resp.StatusCode)
+		}
+	}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/usage", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var u Usage
+	json.NewDecoder(resp.Body).Decode(&u)
+	resp.Body.Close()
+	if u.Used != 1 {
+		t.Errorf("used = %d, want 1", u.Used)
+	}
+
+	// 另一个请求占着队列时, 命中缓存的请求直接返回
+	go search("https://example.com/slow.png")
+	time.Sleep(50 * time.Millisecond)
+	done := make(chan int, 1)
+	go func() {
+		if resp := search("https://example.com/a.png"); resp != nil {
+			done <- resp.StatusCode
+		}
+	}()
+	select {
+	case status := <-done:
+		if status != http.StatusOK {
+			t.Errorf("cached while queued: status %d", status)
+		}
+	case <-time.After(time.Second):
+		t.Error("cached request waited for the queue")
+	}
+}
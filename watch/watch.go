@@ -0,0 +1,309 @@
+// Package watch 轮询目录, 为新增或变更的图片搜图并在旁边写入 sidecar json
+package watch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+	"github.com/Miuzarte/SauceNAO-go/batch"
+)
+
+const (
+	STATE_FILE     = ".saucenao-watch.json"
+	SIDECAR_SUFFIX = ".saucenao.json"
+)
+
+type Watcher struct {
+	Client      *SauceNao.Client
+	Dir         string
+	Interval    time.Duration     // 轮询间隔, 默认 10s
+	Settle      time.Duration     // 文件最后修改后需静置的时间, 避免读到未写完的文件, 默认 2s, 负数不等待
+	StatePath   string            // 已处理的内容哈希, 默认为 Dir 下的 [STATE_FILE]
+	TopN        int               // sidecar 中保留的结果数, 默认 3
+	MaxAttempts int               // 失败文件的最大尝试次数, 达到后直到文件变化才再次处理, 默认 3
+	Extensions  []string          // 默认 [batch.DefaultExtensions]
+	Options     []SauceNao.Option // 传给 [SauceNao.Client.Search]
+	OnResult    func(Event)       // 每处理完一个文件回调一次
+
+	state *State
+	seen  map[string]fileStamp // 上次扫描时的大小与修改时间, 未变化的文件不重复计算哈希
+}
+
+// State 已处理过的内容哈希, 同一内容换了文件名或被复制也不会再次搜索
+type State struct {
+	Hashes    map[string]string      `json:"hashes"`           // sha256 -> 首次处理时的相对路径
+	Failed    map[string]*FailedFile `json:"failed,omitempty"` // 相对路径 -> 失败记录, 处理成功后清除, 文件变化后重新计数
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+type FailedFile struct {
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	Size     int64     `json:"size"` // 与 ModTime 一起判断文件是否被替换
+	ModTime  time.Time `json:"mod_time"`
+}
+
+// Sidecar 写在图片旁边的 {图片名}[SIDECAR_SUFFIX]
+type Sidecar struct {
+	File      string                `json:"file"`
+	Sha256    string                `json:"sha256"`
+	SearchAt  time.Time             `json:"search_at"`
+	Results   []batch.ManifestEntry `json:"results"`
+	Duplicate string                `json:"duplicate_of,omitempty"` // 内容与之前处理过的文件相同
+}
+
+type Event struct {
+	Path    string // 相对 Dir
+	Sidecar *Sidecar
+	Skipped bool // 内容已处理过
+	Err     error
+}
+
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+func (w *Watcher) defaults() {
+	if w.Interval <= 0 {
+		w.Interval = 10 * time.Second
+	}
+	if w.Settle == 0 {
+		w.Settle = 2 * time.Second
+	}
+	if w.StatePath == "" {
+		w.StatePath = filepath.Join(w.Dir, STATE_FILE)
+	}
+	if w.TopN <= 0 {
+		w.TopN = 3
+	}
+	if w.MaxAttempts <= 0 {
+		w.MaxAttempts = 3
+	}
+	if len(w.Extensions) == 0 {
+		w.Extensions = batch.DefaultExtensions
+	}
+}
+
+// Run 持续轮询直到 ctx 结束, 当日次数用尽时等待到窗口结束后继续
+func (w *Watcher) Run(ctx context.Context) error {
+	err := w.load()
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		err = w.Scan(ctx)
+		if errors.Is(err, SauceNao.ErrLongLimit) {
+			// 等到 24h 窗口结束
+			q := w.Client.Quota()
+			timer := time.NewTimer(time.Until(q.UpdatedAt.Add(SauceNao.LONG_WINDOW)))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan 扫描一轮, 处理所有新增或变更的文件
+func (w *Watcher) Scan(ctx context.Context) error {
+	err := w.load()
+	if err != nil {
+		return err
+	}
+	type pendingFile struct {
+		path  string
+		stamp fileStamp
+	}
+	var pending []pendingFile
+	err = filepath.WalkDir(w.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != w.Dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !slices.Contains(w.Extensions, strings.ToLower(filepath.Ext(path))) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // 扫描期间被删除
+		}
+		if time.Since(info.ModTime()) < w.Settle {
+			return nil // 可能还在写入, 下一轮再处理
+		}
+		stamp := fileStamp{info.Size(), info.ModTime()}
+		if w.seen[path] == stamp {
+			return nil
+		}
+		if f := w.state.Failed[w.rel(path)]; f != nil && f.matches(stamp) && f.Attempts >= w.MaxAttempts {
+			w.seen[path] = stamp
+			return nil
+		}
+		pending = append(pending, pendingFile{path, stamp})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 处理成功后才记录, 失败的文件下一轮重试, 直到达到 MaxAttempts
+	for _, p := range pending {
+		ev, err := w.process(ctx, p.path)
+		if err != nil {
+			if errors.Is(err, SauceNao.ErrLongLimit) || ctx.Err() != nil {
+				return err
+			}
+			ev.Err = err
+			if e := w.fail(p.path, p.stamp, err); e != nil {
+				return e
+			}
+		} else {
+			w.seen[p.path] = p.stamp
+		}
+		if w.OnResult != nil {
+			w.OnResult(ev)
+		}
+	}
+	return nil
+}
+
+// fail 记录一次失败, 文件变化后重新计数, 达到 MaxAttempts 后不再处理
+func (w *Watcher) fail(path string, stamp fileStamp, err error) error {
+	rel := w.rel(path)
+	f := w.state.Failed[rel]
+	if f == nil || !f.matches(stamp) {
+		f = &FailedFile{Size: stamp.size, ModTime: stamp.modTime}
+		w.state.Failed[rel] = f
+	}
+	f.Attempts++
+	f.Error = err.Error()
+	if f.Attempts >= w.MaxAttempts {
+		w.seen[path] = stamp
+	}
+	w.state.UpdatedAt = time.Now()
+	return batch.WriteJsonAtomic(w.StatePath, w.state)
+}
+
+// matches 记录的大小与修改时间与 stamp 相同, 从状态文件读取的时间需用 Equal 比较
+func (f *FailedFile) matches(stamp fileStamp) bool {
+	return f.Size == stamp.size && f.ModTime.Equal(stamp.modTime)
+}
+
+func (w *Watcher) rel(path string) string {
+	rel, _ := filepath.Rel(w.Dir, path)
+	return filepath.ToSlash(rel)
+}
+
+// load 首次调用时读取状态文件
+func (w *Watcher) load() error {
+	if w.state != nil {
+		return nil
+	}
+	w.defaults()
+	state := &State{}
+	data, err := os.ReadFile(w.StatePath)
+	switch {
+	case err == nil:
+		err = json.Unmarshal(data, state)
+		if err != nil {
+			return err
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+	if state.Hashes == nil {
+		state.Hashes = map[string]string{}
+	}
+	if state.Failed == nil {
+		state.Failed = map[string]*FailedFile{}
+	}
+	w.state = state
+	w.seen = map[string]fileStamp{}
+	return nil
+}
+
+func (w *Watcher) process(ctx context.Context, path string) (Event, error) {
+	rel := w.rel(path)
+	ev := Event{Path: rel}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ev, err
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if first, ok := w.state.Hashes[hash]; ok {
+		ev.Skipped = true
+		if first != rel {
+			// 复制或重命名的文件, 指向已有的 sidecar
+			if _, err := os.Stat(path + SIDECAR_SUFFIX); errors.Is(err, fs.ErrNotExist) {
+				ev.Sidecar = &Sidecar{File: filepath.Base(path), Sha256: hash, SearchAt: time.Now(), Duplicate: first}
+				return ev, writeSidecar(path, ev.Sidecar)
+			}
+		}
+		return ev, nil
+	}
+
+	resp, err := batch.Search(ctx, w.Client, data, w.Options...)
+	if err != nil {
+		return ev, err
+	}
+
+	ev.Sidecar = &Sidecar{
+		File:     filepath.Base(path),
+		Sha256:   hash,
+		SearchAt: time.Now(),
+		Results:  batch.Entries(resp, w.TopN),
+	}
+	err = writeSidecar(path, ev.Sidecar)
+	if err != nil {
+		return ev, err
+	}
+	w.state.Hashes[hash] = rel
+	delete(w.state.Failed, rel)
+	w.state.UpdatedAt = time.Now()
+	return ev, batch.WriteJsonAtomic(w.StatePath, w.state)
+}
+
+func writeSidecar(imagePath string, sc *Sidecar) error {
+	return batch.WriteJsonAtomic(imagePath+SIDECAR_SUFFIX, sc)
+}
+
+// ReadSidecar 读取图片旁的 sidecar, 不存在时返回 [fs.ErrNotExist]
+func ReadSidecar(imagePath string) (*Sidecar, error) {
+	data, err := os.ReadFile(imagePath + SIDECAR_SUFFIX)
+	if err != nil {
+		return nil, err
+	}
+	sc := &Sidecar{}
+	return sc, json.Unmarshal(data, sc)
+}
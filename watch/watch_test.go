@@ -0,0 +1,147 @@
+package watch
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	SauceNao "github.com/Miuzarte/SauceNAO-go"
+)
+
+func TestScanDedupe(t *testing.T) {
+	searches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		searches++
+		fmt.Fprint(w, `{"header":{"short_limit":"4","long_limit":"100","short_remaining":3,"long_remaining":90,"minimum_similarity":50},
+"results":[{"header":{"similarity":"90.00","index_id":5},"data":{"pixiv_id":1,"member_id":2,"title":"t"}}]}`)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.png"), []byte("a"), 0o644)
+	os.WriteFile(filepath.Join(dir, "copy.png"), []byte("a"), 0o644)
+
+	w := &Watcher{Client: SauceNao.NewClient("key", srv.URL, 0, SauceNao.HIDE_NONE, nil), Dir: dir, Settle: -1}
+	err := w.Scan(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if searches != 1 {
+		t.Fatalf("searches = %d, want 1", searches)
+	}
+	sc, err := ReadSidecar(filepath.Join(dir, "a.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sc.Results) != 1 || sc.Results[0].Urls[0] != "https://www.pixiv.net/artworks/1" {
+		t.Errorf("sidecar = %+v", sc)
+	}
+	sc, err = ReadSidecar(filepath.Join(dir, "copy.png"))
+	if err != nil || sc.Duplicate != "a.png" {
+		t.Errorf("copy sidecar = %+v, %v", sc, err)
+	}
+
+	// 新的 Watcher 从状态文件恢复, 新文件才会搜索
+	os.WriteFile(filepath.Join(dir, "b.png"), []byte("b"), 0o644)
+	w = &Watcher{Client: SauceNao.NewClient("key", srv.URL, 0, SauceNao.HIDE_NONE, nil), Dir: dir, Settle: -1}
+	err = w.Scan(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if searches != 2 {
+		t.Fatalf("searches = %d, want 2", searches)
+	}
+}
+
+func TestScanLongLimitResume(t *testing.T) {
+	longRemaining := 1
+	searches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if longRemaining <= 0 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"header":{"status":-2,"message":"Daily Search Limit Exceeded."}}`)
+			return
+		}
+		longRemaining--
+		searches++
+		fmt.Fprintf(w, `{"header":{"short_limit":"4","long_limit":"100","short_remaining":3,"long_remaining":%d,"minimum_similarity":50},
+"results":[{"header":{"similarity":"90.00","index_id":5},"data":{"pixiv_id":1,"member_id":2,"title":"t"}}]}`, longRemaining)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	for _, name := range []string{"a.png", "b.png", "c.png"} {
+		os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644)
+	}
+
+	w := &Watcher{Client: SauceNao.NewClient("key", srv.URL, 0, SauceNao.HIDE_NONE, nil), Dir: dir, Settle: -1}
+	err := w.Scan(t.Context())
+	if err != SauceNao.ErrLongLimit {
+		t.Fatalf("err = %v, want %v", err, SauceNao.ErrLongLimit)
+	}
+	if searches != 1 {
+		t.Fatalf("searches = %d, want 1", searches)
+	}
+
+	// 额度恢复后同一个 Watcher 继续处理剩下的文件
+	longRemaining = 10
+	w.Client = SauceNao.NewClient("key", srv.URL, 0, SauceNao.HIDE_NONE, nil)
+	err = w.Scan(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if searches != 3 {
+		t.Fatalf("searches = %d, want 3", searches)
+	}
+	for _, name := range []string{"a.png", "b.png", "c.png"} {
+		if _, err := ReadSidecar(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+// 失败的文件最多尝试 MaxAttempts 次, 文件变化后重新计数
+func TestScanMaxAttempts(t *testing.T) {
+	searches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		searches++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.png")
+	os.WriteFile(a, []byte("a"), 0o644)
+	newWatcher := func() *Watcher {
+		return &Watcher{Client: SauceNao.NewClient("key", srv.URL, 0, SauceNao.HIDE_NONE, nil), Dir: dir, Settle: -1, MaxAttempts: 2}
+	}
+
+	w := newWatcher()
+	for range 4 {
+		err := w.Scan(t.Context())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if searches != 2 {
+		t.Fatalf("searches = %d, want 2", searches)
+	}
+
+	// 从状态文件恢复后也不再处理
+	w = newWatcher()
+	w.Scan(t.Context())
+	if searches != 2 {
+		t.Fatalf("after reload: searches = %d, want 2", searches)
+	}
+
+	os.WriteFile(a, []byte("changed"), 0o644)
+	os.Chtimes(a, time.Now(), time.Now().Add(-time.Hour))
+	w.Scan(t.Context())
+	if searches != 3 {
+		t.Fatalf("after change: searches = %d, want 3", searches)
+	}
+}
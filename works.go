@@ -0,0 +1,126 @@
+package SauceNao
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+// Work 为指向同一作品的若干结果
+type Work struct {
+	Links      []db.SiteUrl // 合并去重后的全部链接
+	Similarity float64      // 组内最高相似度
+	Indexes    []db.IndexId // 贡献了结果的索引, 按出现顺序
+	Results    []Result     // 组内结果, 保持原顺序
+}
+
+// Best 返回组内相似度最高的结果
+func (w *Work) Best() Result {
+	best := w.Results[0]
+	for _, r := range w.Results[1:] {
+		if r.Header.SimilarityFloat() > best.Header.SimilarityFloat() {
+			best = r
+		}
+	}
+	return best
+}
+
+// Works 见 [GroupWorks]
+func (r *Response) Works() []Work {
+	return GroupWorks(r.Results)
+}
+
+// GroupWorks 将指向同一作品的结果合并,
+// 依据各结果链接中识别出的作品 id (含 ext_urls 与 booru 的 source),
+// 如 pixiv 结果与 source 指向该 pixiv 作品的 danbooru 结果会合并为一个 [Work].
+// 返回值按最高相似度降序排列
+func GroupWorks(results []Result) []Work {
+	n := len(results)
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	links := make([][]db.SiteUrl, n)
+	owner := map[string]int{} // 作品 key -> 首个出现的结果
+	for i, r := range results {
+		links[i] = r.DecodeData().CanonicalURLs()
+		for _, key := range workKeys(links[i]) {
+			if j, ok := owner[key]; ok {
+				parent[find(i)] = find(j)
+			} else {
+				owner[key] = i
+			}
+		}
+	}
+
+	var works []*Work
+	byRoot := map[int]*Work{}
+	linkSeen := map[int]map[string]bool{} // 已加入的链接, 同一作品的不同链接形式只保留第一个
+	for i, r := range results {
+		root := find(i)
+		w, ok := byRoot[root]
+		if !ok {
+			w = &Work{}
+			byRoot[root] = w
+			linkSeen[root] = map[string]bool{}
+			works = append(works, w)
+		}
+		w.Results = append(w.Results, r)
+		if !slices.Contains(w.Indexes, r.Header.IndexId) {
+			w.Indexes = append(w.Indexes, r.Header.IndexId)
+		}
+		w.Similarity = max(w.Similarity, r.Header.SimilarityFloat())
+		for _, l := range links[i] {
+			key, ok := postKey(l)
+			if !ok {
+				key = l.String()
+			}
+			if !linkSeen[root][key] {
+				linkSeen[root][key] = true
+				w.Links = append(w.Links, l)
+			}
+		}
+	}
+
+	ret := make([]Work, len(works))
+	for i, w := range works {
+		ret[i] = *w
+	}
+	slices.SortStableFunc(ret, func(a, b Work) int {
+		return cmp.Compare(b.Similarity, a.Similarity)
+	})
+	return ret
+}
+
+// workKeys 从链接中提取作品 key, 仅保留能识别到作品 id 的链接,
+// 用户主页等链接不参与合并
+func workKeys(links []db.SiteUrl) []string {
+	var keys []string
+	for _, l := range links {
+		key, ok := postKey(l)
+		if ok && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// postKey 链接指向的作品 key (站点 + 作品 id), 如 pixiv 的 artworks/N 与
+// member_illust.php?illust_id=N 得到相同的 key, 不是作品链接时返回 false
+func postKey(l db.SiteUrl) (string, bool) {
+	si := db.ClassifySource(l.String())
+	if !si.IsPost() {
+		return "", false
+	}
+	return string(si.Site) + ":" + strings.ToLower(si.PostId), true
+}
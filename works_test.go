@@ -0,0 +1,84 @@
+package SauceNao
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Miuzarte/SauceNAO-go/db"
+)
+
+func TestGroupWorks(t *testing.T) {
+	results := []Result{
+		{
+			Header: ResultHeader{Similarity: "95.10", IndexId: db.PIXIV},
+			Data: map[string]any{
+				"ext_urls":    []any{"https://www.pixiv.net/member_illust.php?mode=medium&illust_id=78901234"},
+				"pixiv_id":    78901234,
+				"member_id":   1,
+				"member_name": "artist",
+			},
+		},
+		{
+			Header: ResultHeader{Similarity: "96.20", IndexId: db.DANBOORU},
+			Data: map[string]any{
+				"ext_urls":    []any{"https://danbooru.donmai.us/post/show/1000"},
+				"danbooru_id": 1000,
+				"gelbooru_id": 2000,
+				"source":      "https://i.pximg.net/img-original/img/2020/01/02/03/04/05/78901234_p0.png",
+			},
+		},
+		{
+			Header: ResultHeader{Similarity: "90.00", IndexId: db.GELBOORU},
+			Data: map[string]any{
+				"ext_urls":    []any{"https://gelbooru.com/index.php?page=post&s=view&id=2000"},
+				"gelbooru_id": 2000,
+			},
+		},
+		{
+			// 同一作者的另一作品, 不应合并
+			Header: ResultHeader{Similarity: "50.00", IndexId: db.PIXIV},
+			Data: map[string]any{
+				"pixiv_id":  11111111,
+				"member_id": 1,
+			},
+		},
+		{
+			Header: ResultHeader{Similarity: "40.00", IndexId: db.NIJIE},
+			Data:   map[string]any{},
+		},
+	}
+
+	works := GroupWorks(results)
+	if len(works) != 3 {
+		t.Fatalf("len(works) = %d, want 3", len(works))
+	}
+	w := works[0]
+	if len(w.Results) != 3 {
+		t.Errorf("len(works[0].Results) = %d, want 3", len(w.Results))
+	}
+	if w.Similarity != 96.2 {
+		t.Errorf("works[0].Similarity = %v, want 96.2", w.Similarity)
+	}
+	if len(w.Indexes) != 3 || w.Indexes[0] != db.PIXIV {
+		t.Errorf("works[0].Indexes = %v", w.Indexes)
+	}
+	if best := w.Best(); best.Header.IndexId != db.DANBOORU {
+		t.Errorf("works[0].Best() = %v, want %v", best.Header.IndexId, db.DANBOORU)
+	}
+
+	// 每个作品只保留一条链接: pixiv 的 member_illust.php 与 artworks,
+	// danbooru 的 post/show 与 posts, 以及 danbooru 的 source 与两条 gelbooru 链接
+	want := []string{
+		"pixiv https://www.pixiv.net/artworks/78901234",
+		"pixiv https://www.pixiv.net/users/1",
+		"danbooru https://danbooru.donmai.us/posts/1000",
+		"gelbooru https://gelbooru.com/index.php?page=post&s=view&id=2000",
+	}
+	var links []string
+	for _, l := range w.Links {
+		links = append(links, string(l.Site)+" "+l.String())
+	}
+	if !slices.Equal(links, want) {
+		t.Errorf("works[0].Links = %q, want %q", links, want)
+	}
+}